@@ -0,0 +1,101 @@
+package stealthim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrConfirmationRequired is returned by Delete without a valid confirmation from RequestDelete
+var ErrConfirmationRequired = errors.New("account deletion requires a valid confirmation")
+
+// DeleteConfirmationTTL is how long a confirmation from RequestDelete stays valid
+const DeleteConfirmationTTL = 5 * time.Minute
+
+// DeleteConfirmation authorizes a single call to User.Delete, see RequestDelete
+type DeleteConfirmation struct {
+	Token   string
+	Expires time.Time
+}
+
+// VerifyPassword checks the current password of the account by logging in with a separate
+// session, which is logged out again right away. The user's own session is not touched.
+func (u *User) VerifyPassword(ctx context.Context, password string) error {
+	username, err := u.client.selfUsername(ctx)
+	if err != nil {
+		return fmt.Errorf("re-authentication failed: %w", err)
+	}
+	if username == "" {
+		return errors.New("re-authentication failed: username of the session is unknown")
+	}
+
+	probeUser, _, err := NewServerWithClient(u.client.clone()).Login(ctx, username, password)
+	if err != nil {
+		return fmt.Errorf("re-authentication failed: %w", err)
+	}
+	// Best effort, servers without logout let the session expire
+	probeUser.Logout(ctx)
+	return nil
+}
+
+// ChangePassword changes the password after checking the current one, then logs in again
+// with the new password since the server may revoke existing sessions on a password change
+func (u *User) ChangePassword(ctx context.Context, oldPassword, newPassword string) error {
+	if err := ValidatePassword(newPassword); err != nil {
+		return err
+	}
+	if err := u.VerifyPassword(ctx, oldPassword); err != nil {
+		return err
+	}
+	if err := u.changePassword(ctx, newPassword); err != nil {
+		return err
+	}
+
+	username, err := u.client.selfUsername(ctx)
+	if err != nil {
+		return fmt.Errorf("password changed but refreshing the session failed: %w", err)
+	}
+	_, info, err := NewServerWithClient(u.client).Login(ctx, username, newPassword)
+	if err != nil {
+		return fmt.Errorf("password changed but refreshing the session failed: %w", err)
+	}
+	u.setInfo(*info)
+	return nil
+}
+
+// RequestDelete checks the password and returns a confirmation that allows one call to Delete
+// within DeleteConfirmationTTL. Requesting a new confirmation invalidates the previous one.
+func (u *User) RequestDelete(ctx context.Context, password string) (*DeleteConfirmation, error) {
+	if err := u.VerifyPassword(ctx, password); err != nil {
+		return nil, err
+	}
+
+	token, err := GenerateRandomString(32)
+	if err != nil {
+		return nil, err
+	}
+	confirm := &DeleteConfirmation{Token: token, Expires: time.Now().Add(DeleteConfirmationTTL)}
+
+	u.mu.Lock()
+	u.pendingDelete = confirm
+	u.mu.Unlock()
+
+	return confirm, nil
+}
+
+// consumeDeleteConfirmation checks confirm against the pending confirmation and invalidates it
+func (u *User) consumeDeleteConfirmation(confirm *DeleteConfirmation) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	pending := u.pendingDelete
+	if confirm == nil || pending == nil || confirm.Token != pending.Token {
+		return ErrConfirmationRequired
+	}
+	u.pendingDelete = nil
+	if time.Now().After(pending.Expires) {
+		return fmt.Errorf("%w: confirmation expired", ErrConfirmationRequired)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package stealthim
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+// TestUserChangePasswordReauth tests that User.ChangePassword checks the old password and keeps the user logged in
+func TestUserChangePasswordReauth(t *testing.T) {
+	fs := newFakeServer(t)
+	user := fs.login(t, "alice")
+	ctx := context.Background()
+
+	if err := user.ChangePassword(ctx, "Wrong1234", "Cd987654"); resultCode(err) != fakeCodeUserPasswordError {
+		t.Fatalf("Expected a wrong old password to be rejected, got %v", err)
+	}
+	if err := user.ChangePassword(ctx, "Ab123456", "weak"); !errors.Is(err, ErrValidation) {
+		t.Fatalf("Expected a validation error for a weak password, got %v", err)
+	}
+
+	sessionCount := func() int {
+		fs.mu.Lock()
+		defer fs.mu.Unlock()
+		return len(fs.sessions)
+	}
+	sessions := sessionCount()
+	if err := user.ChangePassword(ctx, "Ab123456", "Cd987654"); err != nil {
+		t.Fatalf("ChangePassword failed: %v", err)
+	}
+	if _, err := user.GetSelfInfo(ctx); err != nil {
+		t.Errorf("Expected the session to be refreshed, got %v", err)
+	}
+	if n := sessionCount(); n != sessions {
+		t.Errorf("Expected re-authentication sessions to be logged out, got %d sessions", n)
+	}
+	if _, _, err := NewServer(fs.URL).Login(ctx, "alice", "Cd987654"); err != nil {
+		t.Errorf("Expected to log in with the new password, got %v", err)
+	}
+}
+
+// TestUserDeleteConfirmation tests that User.Delete only works with a fresh confirmation
+func TestUserDeleteConfirmation(t *testing.T) {
+	fs := newFakeServer(t)
+	user := fs.login(t, "alice")
+	ctx := context.Background()
+
+	if err := user.Delete(ctx, nil); !errors.Is(err, ErrConfirmationRequired) {
+		t.Fatalf("Expected ErrConfirmationRequired without confirmation, got %v", err)
+	}
+	if err := user.Delete(ctx, &DeleteConfirmation{Token: "guess"}); !errors.Is(err, ErrConfirmationRequired) {
+		t.Fatalf("Expected ErrConfirmationRequired for a forged confirmation, got %v", err)
+	}
+	if _, err := user.RequestDelete(ctx, "Wrong1234"); resultCode(err) != fakeCodeUserPasswordError {
+		t.Fatalf("Expected a wrong password to be rejected, got %v", err)
+	}
+
+	// A newer confirmation replaces the older one
+	stale, err := user.RequestDelete(ctx, "Ab123456")
+	if err != nil {
+		t.Fatalf("RequestDelete failed: %v", err)
+	}
+	confirm, err := user.RequestDelete(ctx, "Ab123456")
+	if err != nil {
+		t.Fatalf("RequestDelete failed: %v", err)
+	}
+	if err := user.Delete(ctx, stale); !errors.Is(err, ErrConfirmationRequired) {
+		t.Errorf("Expected the stale confirmation to be rejected, got %v", err)
+	}
+	if err := user.Delete(ctx, confirm); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	fs.mu.Lock()
+	_, exists := fs.users["alice"]
+	fs.mu.Unlock()
+	if exists {
+		t.Error("Expected the account to be deleted")
+	}
+	if err := user.Delete(ctx, confirm); !errors.Is(err, ErrConfirmationRequired) {
+		t.Errorf("Expected a confirmation to be usable once, got %v", err)
+	}
+}
+
+// TestUserVerifyPasswordRestoredSession tests re-authentication for a client restored from a session
+func TestUserVerifyPasswordRestoredSession(t *testing.T) {
+	fs := newFakeServer(t)
+	alice := fs.login(t, "alice")
+	client := NewClientWithSession(fs.URL, alice.client.Session)
+	var calls []string
+	client.Use(func(ctx context.Context, call *Call, next Invoker) (*Reply, error) {
+		calls = append(calls, call.Method+" "+call.Endpoint)
+		return next(ctx, call)
+	})
+	user := &User{client: client}
+	ctx := context.Background()
+
+	if err := user.VerifyPassword(ctx, "Ab123456"); err != nil {
+		t.Fatalf("VerifyPassword failed: %v", err)
+	}
+	if err := user.VerifyPassword(ctx, "Wrong1234"); resultCode(err) != fakeCodeUserPasswordError {
+		t.Errorf("Expected a wrong password to be rejected, got %v", err)
+	}
+	// The re-authentication login goes through the interceptors of the client
+	if !slices.Contains(calls, "POST /api/v1/user") {
+		t.Errorf("Expected the login to pass the interceptors, got %v", calls)
+	}
+	if client.session() != alice.client.Session {
+		t.Error("Expected the session of the client to be kept")
+	}
+}
@@ -2,10 +2,7 @@ package stealthim
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
 	"os"
 )
 
@@ -29,42 +26,31 @@ func NewServerFromEnv() *Server {
 	}
 }
 
-// Ping checks if the server is available
-func (s *Server) Ping(ctx context.Context) error {
-	resp, err := s.client.doRequest(ctx, "GET", "/api/v1/ping", nil)
-	if err != nil {
-		return fmt.Errorf("ping request failed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// Ping API returns a simple message, not a Result structure
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("ping request failed with status: %d", resp.StatusCode)
-	}
-
-	// Read the response body to check if it's valid
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return fmt.Errorf("failed to read ping response: %w", err)
-	}
-
-	// Check if the response contains the expected message
-	var pingResp map[string]any
-	if err := json.Unmarshal(body, &pingResp); err != nil {
-		return fmt.Errorf("failed to parse ping response: %w", err)
-	}
-
-	if msg, ok := pingResp["message"]; !ok {
-		return fmt.Errorf("ping response does not contain message field")
-	} else if msgStr, ok := msg.(string); !ok || msgStr == "" {
-		return fmt.Errorf("ping message is not a valid string")
+// NewServerWithClient creates a new server instance backed by an existing client,
+// which allows the HTTP client, session and logger to be configured by the caller
+func NewServerWithClient(client *Client) *Server {
+	return &Server{
+		client: client,
 	}
+}
 
-	return nil
+// Ping checks if the server is available and speaks a compatible API version
+func (s *Server) Ping(ctx context.Context) error {
+	_, err := s.client.fetchServerInfo(ctx)
+	return err
 }
 
-// Register registers a new user
+// Register registers a new user. Invalid fields are reported as ValidationErrors before any request is sent.
 func (s *Server) Register(ctx context.Context, username, password, nickname, email, phoneNumber string) (*UserInfo, error) {
+	if err := validateFields(map[string]string{
+		"username":     username,
+		"password":     password,
+		"email":        email,
+		"phone_number": phoneNumber,
+	}, "username", "password"); err != nil {
+		return nil, err
+	}
+
 	reqBody := map[string]any{
 		"username":     username,
 		"password":     password,
@@ -80,7 +66,8 @@ func (s *Server) Register(ctx context.Context, username, password, nickname, ema
 	defer resp.Body.Close()
 
 	var response struct {
-		Result Result `json:"result"`
+		Result   Result    `json:"result"`
+		UserInfo *UserInfo `json:"user_info"`
 	}
 	if err := s.client.parseResponse(resp, &response); err != nil {
 		return nil, fmt.Errorf("failed to parse register response: %w", err)
@@ -90,8 +77,29 @@ func (s *Server) Register(ctx context.Context, username, password, nickname, ema
 		return nil, response.Result.ToError()
 	}
 
-	// For register, we just return success since it doesn't return session/user_info like login
-	return nil, nil
+	// The server may not echo the new account, fall back to what was submitted
+	if response.UserInfo != nil {
+		return response.UserInfo, nil
+	}
+	return &UserInfo{
+		Username:    username,
+		Nickname:    nickname,
+		Email:       email,
+		PhoneNumber: phoneNumber,
+	}, nil
+}
+
+// RegisterAndLogin registers a new user and logs in straight away
+func (s *Server) RegisterAndLogin(ctx context.Context, username, password, nickname, email, phoneNumber string) (*User, *UserInfo, error) {
+	if _, err := s.Register(ctx, username, password, nickname, email, phoneNumber); err != nil {
+		return nil, nil, err
+	}
+
+	user, info, err := s.Login(ctx, username, password)
+	if err != nil {
+		return nil, nil, fmt.Errorf("registered %s but login failed: %w", username, err)
+	}
+	return user, info, nil
 }
 
 // Login authenticates a user and returns user information
@@ -121,7 +129,10 @@ func (s *Server) Login(ctx context.Context, username, password string) (*User, *
 	}
 
 	// Update client with session
-	s.client.Session = response.Session
+	if response.UserInfo.Username != "" {
+		username = response.UserInfo.Username
+	}
+	s.client.setSession(response.Session, username)
 
 	user := &User{
 		client: s.client,
@@ -2,6 +2,7 @@ package stealthim
 
 import (
 	"context"
+	"errors"
 	"os"
 	"testing"
 	"time"
@@ -117,4 +118,33 @@ func TestServerPingContextCancellation(t *testing.T) {
 	if err == nil {
 		t.Error("Expected error for cancelled context")
 	}
-}
\ No newline at end of file
+}
+
+// TestServerRegisterAndLogin tests the Server.RegisterAndLogin method
+func TestServerRegisterAndLogin(t *testing.T) {
+	fs := newFakeServer(t)
+	server := NewServer(fs.URL)
+	ctx := context.Background()
+
+	info, err := server.Register(ctx, "alice", "Ab123456", "Alice", "alice@example.com", "1234567890")
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	if info == nil || info.Username != "alice" || info.Nickname != "Alice" || info.CreateTime.IsZero() {
+		t.Errorf("Unexpected registered user: %+v", info)
+	}
+
+	user, info, err := server.RegisterAndLogin(ctx, "bob", "Ab123456", "Bob", "bob@example.com", "1234567890")
+	if err != nil {
+		t.Fatalf("RegisterAndLogin failed: %v", err)
+	}
+	if user == nil || info.Username != "bob" || server.client.Session == "" {
+		t.Errorf("Expected to be logged in as bob, got %+v", info)
+	}
+
+	// A taken username is only known to the server, its rejection is passed on
+	_, _, err = server.RegisterAndLogin(ctx, "alice", "Ab123456", "Alice", "alice@example.com", "1234567890")
+	if resultCode(err) != fakeCodeUserAlreadyExists || errors.Is(err, ErrValidation) {
+		t.Errorf("Expected the server's rejection, got %v", err)
+	}
+}
@@ -0,0 +1,157 @@
+package stealthim
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DefaultBlockSize is the upload block size used when the server does not advertise one
+const DefaultBlockSize int64 = 2048 * 1024 // 2048 KiB
+
+// SupportedAPIVersion is the server API version this SDK speaks
+const SupportedAPIVersion = "v1"
+
+// Capability errors
+var (
+	ErrIncompatibleServer     = errors.New("incompatible server")
+	ErrFileTooLarge           = errors.New("file too large")
+	ErrUnsupportedMessageType = errors.New("unsupported message type")
+	ErrNotSupported           = errors.New("not supported by the server")
+)
+
+// ServerInfo describes the server version and the features it supports
+type ServerInfo struct {
+	Message    string         `json:"message"`
+	Version    string         `json:"version"`
+	APIVersion string         `json:"api_version"`
+	Features   ServerFeatures `json:"features"`
+}
+
+// ServerFeatures describes optional server features and limits
+type ServerFeatures struct {
+	BlockSize    int64         `json:"block_size"`    // Upload and hash block size in bytes
+	MaxFileSize  int64         `json:"max_file_size"` // Maximum upload size in bytes, 0 if unlimited
+	MessageTypes []MessageType `json:"message_types"` // Message types accepted by the server
+	RangeSupport bool          `json:"range"`         // Whether file downloads accept Range headers
+}
+
+// defaultMessageTypes lists the message types every v1 server accepts
+var defaultMessageTypes = []MessageType{Text, Image, LargeEmoji, Emoji, File, Card, InnerLink, RecallText}
+
+// SupportsMessageType reports whether the server accepts the given message type
+func (f *ServerFeatures) SupportsMessageType(msgType MessageType) bool {
+	for _, t := range f.MessageTypes {
+		if t == msgType {
+			return true
+		}
+	}
+	return false
+}
+
+// applyDefaults fills in values for fields that older servers do not report
+func (i *ServerInfo) applyDefaults() {
+	if i.APIVersion == "" {
+		i.APIVersion = SupportedAPIVersion
+	}
+	if i.Features.BlockSize <= 0 {
+		i.Features.BlockSize = DefaultBlockSize
+	}
+	if len(i.Features.MessageTypes) == 0 {
+		i.Features.MessageTypes = defaultMessageTypes
+	}
+}
+
+// checkCompatible returns ErrIncompatibleServer if the server speaks another major API version
+func (i *ServerInfo) checkCompatible() error {
+	major, _, _ := strings.Cut(i.APIVersion, ".")
+	if major != SupportedAPIVersion {
+		return fmt.Errorf("%w: server API version %s, SDK supports %s", ErrIncompatibleServer, i.APIVersion, SupportedAPIVersion)
+	}
+	return nil
+}
+
+// Info retrieves the server version and capabilities and caches them on the client.
+// Fields missing from the response fall back to the defaults of API version v1.
+func (s *Server) Info(ctx context.Context) (*ServerInfo, error) {
+	return s.client.fetchServerInfo(ctx)
+}
+
+// fetchServerInfo queries the ping endpoint for server information and caches the result
+func (c *Client) fetchServerInfo(ctx context.Context) (*ServerInfo, error) {
+	resp, err := c.doRequest(ctx, "GET", "/api/v1/ping", nil)
+	if err != nil {
+		return nil, fmt.Errorf("ping request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Ping API returns a simple message, not a Result structure
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ping request failed with status: %d", resp.StatusCode)
+	}
+
+	// Read the response body to check if it's valid
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ping response: %w", err)
+	}
+
+	// Check if the response contains the expected message
+	var pingResp map[string]any
+	if err := json.Unmarshal(body, &pingResp); err != nil {
+		return nil, fmt.Errorf("failed to parse ping response: %w", err)
+	}
+
+	if msg, ok := pingResp["message"]; !ok {
+		return nil, fmt.Errorf("ping response does not contain message field")
+	} else if msgStr, ok := msg.(string); !ok || msgStr == "" {
+		return nil, fmt.Errorf("ping message is not a valid string")
+	}
+
+	var info ServerInfo
+	if err := json.Unmarshal(body, &info); err != nil {
+		return nil, fmt.Errorf("failed to parse server info: %w", err)
+	}
+	info.applyDefaults()
+	if err := info.checkCompatible(); err != nil {
+		return nil, err
+	}
+
+	c.infoMu.Lock()
+	c.info = &info
+	c.infoMu.Unlock()
+
+	return &info, nil
+}
+
+// cachedServerInfo returns the server information from the last successful Info call, or nil
+func (c *Client) cachedServerInfo() *ServerInfo {
+	c.infoMu.Lock()
+	defer c.infoMu.Unlock()
+	return c.info
+}
+
+// serverInfo returns the cached server information, fetching it on first use
+func (c *Client) serverInfo(ctx context.Context) (*ServerInfo, error) {
+	if info := c.cachedServerInfo(); info != nil {
+		return info, nil
+	}
+	return c.fetchServerInfo(ctx)
+}
+
+// uploadLimits returns the block size and maximum file size for uploads.
+// Defaults are used if the server cannot be introspected, but incompatible servers are reported.
+func (c *Client) uploadLimits(ctx context.Context) (blockSize, maxFileSize int64, err error) {
+	info, err := c.serverInfo(ctx)
+	if err != nil {
+		if errors.Is(err, ErrIncompatibleServer) {
+			return 0, 0, err
+		}
+		return DefaultBlockSize, 0, nil
+	}
+	return info.Features.BlockSize, info.Features.MaxFileSize, nil
+}
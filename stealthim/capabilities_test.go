@@ -0,0 +1,90 @@
+package stealthim
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newPingServer starts a test server answering the ping endpoint with body
+func newPingServer(body string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+}
+
+// TestServerInfo tests that Server.Info reads the advertised capabilities
+func TestServerInfo(t *testing.T) {
+	ts := newPingServer(`{"message":"pong","version":"1.4.0","api_version":"v1","features":{"block_size":1048576,"max_file_size":4096,"message_types":[0,4],"range":true}}`)
+	defer ts.Close()
+
+	server := NewServer(ts.URL)
+	info, err := server.Info(context.Background())
+	if err != nil {
+		t.Fatalf("Info failed: %v", err)
+	}
+
+	if info.Version != "1.4.0" || info.APIVersion != "v1" {
+		t.Errorf("Unexpected versions: %+v", info)
+	}
+	if info.Features.BlockSize != 1048576 || info.Features.MaxFileSize != 4096 || !info.Features.RangeSupport {
+		t.Errorf("Unexpected features: %+v", info.Features)
+	}
+	if !info.Features.SupportsMessageType(File) || info.Features.SupportsMessageType(Image) {
+		t.Errorf("Unexpected message types: %v", info.Features.MessageTypes)
+	}
+
+	blockSize, maxFileSize, err := server.client.uploadLimits(context.Background())
+	if err != nil || blockSize != 1048576 || maxFileSize != 4096 {
+		t.Errorf("Unexpected upload limits: %d %d %v", blockSize, maxFileSize, err)
+	}
+}
+
+// TestServerInfoDefaults tests the defaults used for servers that only report a message
+func TestServerInfoDefaults(t *testing.T) {
+	ts := newPingServer(`{"message":"pong"}`)
+	defer ts.Close()
+
+	info, err := NewServer(ts.URL).Info(context.Background())
+	if err != nil {
+		t.Fatalf("Info failed: %v", err)
+	}
+	if info.APIVersion != SupportedAPIVersion {
+		t.Errorf("Expected default API version, got %s", info.APIVersion)
+	}
+	if info.Features.BlockSize != DefaultBlockSize {
+		t.Errorf("Expected default block size, got %d", info.Features.BlockSize)
+	}
+	if !info.Features.SupportsMessageType(RecallText) {
+		t.Error("Expected default message types to include RecallText")
+	}
+}
+
+// TestServerInfoIncompatible tests that servers with another major API version are rejected
+func TestServerInfoIncompatible(t *testing.T) {
+	ts := newPingServer(`{"message":"pong","api_version":"v2"}`)
+	defer ts.Close()
+
+	server := NewServer(ts.URL)
+	if err := server.Ping(context.Background()); !errors.Is(err, ErrIncompatibleServer) {
+		t.Errorf("Expected ErrIncompatibleServer, got %v", err)
+	}
+}
+
+// TestSendMessageUnsupportedType tests that known unsupported message types are rejected locally
+func TestSendMessageUnsupportedType(t *testing.T) {
+	ts := newPingServer(`{"message":"pong","features":{"message_types":[0]}}`)
+	defer ts.Close()
+
+	server := NewServer(ts.URL)
+	if _, err := server.Info(context.Background()); err != nil {
+		t.Fatalf("Info failed: %v", err)
+	}
+
+	group := &Group{client: server.client, GroupID: 1}
+	if _, err := group.SendMessage(context.Background(), Card, "{}"); !errors.Is(err, ErrUnsupportedMessageType) {
+		t.Errorf("Expected ErrUnsupportedMessageType, got %v", err)
+	}
+}
@@ -4,18 +4,55 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"os"
+	"sync"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Client represents the HTTP client for API requests
 type Client struct {
 	BaseURL    string
 	HTTPClient *http.Client
-	Session    string
+
+	// Session is set by Login and cleared by Logout. Set it before the client is shared
+	// between goroutines; the SDK itself only accesses it under sessionMu.
+	Session string
+
+	// Logger receives structured events about requests, reconnects and file transfers.
+	// Sessions and passwords are redacted. Logging is disabled when nil.
+	Logger *slog.Logger
+
+	// TracerProvider, MeterProvider and Propagator configure OpenTelemetry instrumentation.
+	// The global OpenTelemetry providers are used when nil.
+	TracerProvider trace.TracerProvider
+	MeterProvider  metric.MeterProvider
+	Propagator     propagation.TextMapPropagator
+
+	// Interceptors wrap every API call made through the client, see Use
+	Interceptors []Interceptor
+
+	// Endpoints enables failover between several base URLs, see NewMultiServer.
+	// BaseURL is ignored when set.
+	Endpoints *EndpointPool
+
+	telemetryOnce sync.Once
+	tel           *telemetry
+
+	infoMu sync.Mutex
+	info   *ServerInfo // Cached server capabilities, see Server.Info
+
+	sessionMu sync.RWMutex // Guards Session and username against concurrent Login and Logout
+	username  string       // Username the session belongs to, set by Login
 }
 
 // NewClient creates a new API client
@@ -50,9 +87,58 @@ func NewClientFromEnv() *Client {
 	}
 }
 
-// doRequest performs an HTTP request with proper headers and session management
+// clone returns a client with the same settings but without a session, for requests
+// that must not use or replace the session of c
+func (c *Client) clone() *Client {
+	return &Client{
+		BaseURL:        c.BaseURL,
+		HTTPClient:     c.HTTPClient,
+		Logger:         c.Logger,
+		TracerProvider: c.TracerProvider,
+		MeterProvider:  c.MeterProvider,
+		Propagator:     c.Propagator,
+		Interceptors:   c.Interceptors,
+		Endpoints:      c.Endpoints,
+	}
+}
+
+// doRequest performs an HTTP request through the interceptor chain.
+// The returned response body is buffered and can be read by the caller as usual.
 func (c *Client) doRequest(ctx context.Context, method, endpoint string, body any) (*http.Response, error) {
-	url := c.BaseURL + endpoint
+	call := &Call{
+		Method:   method,
+		Endpoint: endpoint,
+		Body:     body,
+		Header:   make(http.Header),
+	}
+	reply, err := c.invoke(ctx, call, c.send)
+	if err != nil {
+		return nil, err
+	}
+	return reply.response(), nil
+}
+
+// newRequest creates an HTTP request for call with interceptor headers, trace context and session
+func (c *Client) newRequest(ctx context.Context, call *Call, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, call.Method, c.baseURL(ctx)+call.Endpoint, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	for key, values := range call.Header {
+		req.Header[key] = values
+	}
+	c.telemetry().inject(ctx, req.Header)
+
+	// Set authorization header if session is available
+	if session := c.session(); session != "" {
+		req.Header.Set("Authorization", "Bearer "+session)
+	}
+	return req, nil
+}
+
+// send performs the HTTP request described by call with proper headers and session management
+func (c *Client) send(ctx context.Context, call *Call) (*Reply, error) {
+	method, endpoint, body := call.Method, call.Endpoint, call.Body
 
 	var bodyReader io.Reader
 	if body != nil {
@@ -63,25 +149,101 @@ func (c *Client) doRequest(ctx context.Context, method, endpoint string, body an
 		bodyReader = bytes.NewBuffer(jsonData)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, method, url, bodyReader)
+	tel := c.telemetry()
+	route := routeTemplate(endpoint)
+	metricAttrs := []attribute.KeyValue{
+		attribute.String("http.request.method", method),
+		attribute.String("http.route", route),
+	}
+	ctx, span := tel.startSpan(ctx, "StealthIM "+method+" "+route,
+		attribute.String("http.request.method", method),
+		attribute.String("http.route", route),
+		attribute.String("url.path", endpoint),
+	)
+	defer span.End()
+
+	req, err := c.newRequest(ctx, call, bodyReader)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, err
 	}
 
 	// Set content type
 	req.Header.Set("Content-Type", "application/json")
 
-	// Set authorization header if session is available
-	if c.Session != "" {
-		req.Header.Set("Authorization", "Bearer "+c.Session)
+	start := time.Now()
+	resp, err := c.HTTPClient.Do(req)
+	tel.requestDuration.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(metricAttrs...))
+	if err != nil {
+		tel.recordError(ctx, span, err, 0, metricAttrs...)
+		c.logger().LogAttrs(ctx, slog.LevelWarn, "request failed",
+			slog.String("method", method),
+			slog.String("endpoint", endpoint),
+			slog.Duration("latency", time.Since(start)),
+			slog.Any("body", redactedBody{body}),
+			slog.Any("error", err),
+		)
+		return nil, &transportError{op: "failed to execute request", err: err}
 	}
 
-	resp, err := c.HTTPClient.Do(req)
+	// Buffer the response so the result code can be logged before the caller parses it
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute request: %w", err)
+		tel.recordError(ctx, span, err, 0, metricAttrs...)
+		return nil, &transportError{op: "failed to read response body", err: err}
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+
+	var peek struct {
+		Result *Result `json:"result"`
+	}
+	_ = json.Unmarshal(respBody, &peek)
+
+	level := slog.LevelDebug
+	attrs := []slog.Attr{
+		slog.String("method", method),
+		slog.String("endpoint", endpoint),
+		slog.Int("status", resp.StatusCode),
+		slog.Duration("latency", time.Since(start)),
+		slog.Any("body", redactedBody{body}),
+	}
+	if peek.Result != nil {
+		attrs = append(attrs, slog.Int("code", peek.Result.Code))
+		span.SetAttributes(attribute.Int("stealthim.result.code", peek.Result.Code))
+		if !peek.Result.IsSuccess() {
+			level = slog.LevelWarn
+			attrs = append(attrs, slog.String("result_msg", peek.Result.Msg))
+			tel.recordError(ctx, span, peek.Result.ToError(), peek.Result.Code, metricAttrs...)
+		}
+	} else if resp.StatusCode >= http.StatusBadRequest {
+		level = slog.LevelWarn
+		tel.recordError(ctx, span, fmt.Errorf("request failed with status: %d", resp.StatusCode), 0, metricAttrs...)
 	}
+	c.logger().LogAttrs(ctx, level, "request completed", attrs...)
 
-	return resp, nil
+	reply := &Reply{
+		Response: resp,
+		Body:     respBody,
+	}
+	if peek.Result != nil {
+		reply.Result = *peek.Result
+	}
+	return reply, nil
+}
+
+// stream opens a streaming request described by call without buffering the response body
+func (c *Client) stream(ctx context.Context, call *Call) (*Reply, error) {
+	req, err := c.newRequest(ctx, call, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, &transportError{op: "stream request failed", err: err}
+	}
+	return &Reply{Response: resp}, nil
 }
 
 // parseResponse parses the JSON response into the provided result structure
@@ -99,3 +261,45 @@ func (c *Client) parseResponse(resp *http.Response, result any) error {
 
 	return nil
 }
+
+// selfUsername returns the username the session belongs to. Clients restored from a session
+// do not know it until it has been looked up once.
+func (c *Client) selfUsername(ctx context.Context) (string, error) {
+	if username := c.currentUsername(); username != "" {
+		return username, nil
+	}
+	info, err := (&User{client: c}).GetSelfInfo(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve the current user: %w", err)
+	}
+	if info.Username == "" {
+		return "", errors.New("server did not report the current username")
+	}
+	c.sessionMu.Lock()
+	if c.username == "" {
+		c.username = info.Username
+	}
+	c.sessionMu.Unlock()
+	return info.Username, nil
+}
+
+// session returns the current session token
+func (c *Client) session() string {
+	c.sessionMu.RLock()
+	defer c.sessionMu.RUnlock()
+	return c.Session
+}
+
+// currentUsername returns the username the session belongs to, empty if it is not known yet
+func (c *Client) currentUsername() string {
+	c.sessionMu.RLock()
+	defer c.sessionMu.RUnlock()
+	return c.username
+}
+
+// setSession replaces the session and the username it belongs to
+func (c *Client) setSession(session, username string) {
+	c.sessionMu.Lock()
+	c.Session, c.username = session, username
+	c.sessionMu.Unlock()
+}
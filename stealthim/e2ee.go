@@ -0,0 +1,573 @@
+package stealthim
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// E2EE errors
+var (
+	ErrNoGroupKey        = errors.New("no group key available")
+	ErrMissingPublicKey  = errors.New("missing public key")
+	ErrDecryptionFailed  = errors.New("decryption failed")
+	ErrPublicKeyMismatch = errors.New("public key does not match the known key")
+)
+
+// Prefixes of the message payloads used by the E2EE layer. All of them are sent as Text
+// messages, so the server and clients without E2EE support see opaque text.
+const (
+	e2eeMessagePrefix  = "stim-e2ee:1:"
+	e2eeKeyPrefix      = "stim-e2ee-key:1:"
+	e2eeAnnouncePrefix = "stim-e2ee-pub:1:"
+)
+
+// groupKeySize is the size of the per-group AES-256 key
+const groupKeySize = 32
+
+// KeyExchange distributes group keys between members. Implementations hold the member's
+// long-term key pair; public keys are exchanged through the group, see E2EEGroup.
+type KeyExchange interface {
+	// PublicKey returns the public key other members seal group keys for
+	PublicKey() []byte
+	// SealKey encrypts a group key for the member owning recipientPublicKey
+	SealKey(groupKey, recipientPublicKey []byte) ([]byte, error)
+	// OpenKey decrypts a group key that the member owning senderPublicKey sealed for us
+	OpenKey(sealed, senderPublicKey []byte) ([]byte, error)
+}
+
+// X25519KeyExchange seals group keys with AES-GCM under a key derived from an X25519
+// agreement between the sender's and the recipient's long-term keys
+type X25519KeyExchange struct {
+	private *ecdh.PrivateKey
+}
+
+// NewX25519KeyExchange generates a new X25519 key pair
+func NewX25519KeyExchange() (*X25519KeyExchange, error) {
+	private, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate X25519 key: %w", err)
+	}
+	return &X25519KeyExchange{private: private}, nil
+}
+
+// LoadX25519KeyExchange restores a key pair from PrivateKey
+func LoadX25519KeyExchange(privateKey []byte) (*X25519KeyExchange, error) {
+	private, err := ecdh.X25519().NewPrivateKey(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid X25519 private key: %w", err)
+	}
+	return &X25519KeyExchange{private: private}, nil
+}
+
+// PrivateKey returns the private key so it can be stored and loaded again
+func (x *X25519KeyExchange) PrivateKey() []byte {
+	return x.private.Bytes()
+}
+
+// PublicKey returns the X25519 public key
+func (x *X25519KeyExchange) PublicKey() []byte {
+	return x.private.PublicKey().Bytes()
+}
+
+// SealKey encrypts groupKey for the owner of recipientPublicKey
+func (x *X25519KeyExchange) SealKey(groupKey, recipientPublicKey []byte) ([]byte, error) {
+	aead, err := x.keyEncryption(x.PublicKey(), recipientPublicKey, recipientPublicKey)
+	if err != nil {
+		return nil, err
+	}
+	return seal(aead, groupKey, nil)
+}
+
+// OpenKey decrypts a group key sealed for us by the owner of senderPublicKey
+func (x *X25519KeyExchange) OpenKey(sealed, senderPublicKey []byte) ([]byte, error) {
+	aead, err := x.keyEncryption(senderPublicKey, x.PublicKey(), senderPublicKey)
+	if err != nil {
+		return nil, err
+	}
+	return open(aead, sealed, nil)
+}
+
+// keyEncryption derives the AEAD that protects group keys sent from sender to recipient
+func (x *X25519KeyExchange) keyEncryption(sender, recipient, peer []byte) (cipher.AEAD, error) {
+	peerKey, err := ecdh.X25519().NewPublicKey(peer)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMissingPublicKey, err)
+	}
+	shared, err := x.private.ECDH(peerKey)
+	if err != nil {
+		return nil, fmt.Errorf("key agreement failed: %w", err)
+	}
+
+	h := sha256.New()
+	h.Write([]byte("stealthim e2ee key encryption"))
+	h.Write(shared)
+	h.Write(sender)
+	h.Write(recipient)
+	return newAEAD(h.Sum(nil))
+}
+
+// newAEAD creates an AES-GCM cipher
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts plaintext with a random nonce and returns nonce and ciphertext
+func seal(aead cipher.AEAD, plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// open decrypts the output of seal
+func open(aead cipher.AEAD, sealed, additionalData []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, ErrDecryptionFailed
+	}
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], additionalData)
+	if err != nil {
+		return nil, ErrDecryptionFailed
+	}
+	return plaintext, nil
+}
+
+// e2eePayload is the plaintext of an encrypted message
+type e2eePayload struct {
+	Type MessageType `json:"type"`
+	Msg  string      `json:"msg"`
+}
+
+// e2eeKeyMessage distributes a new group key, sealed for each member
+type e2eeKeyMessage struct {
+	KeyID     uint32            `json:"key_id"`
+	PublicKey []byte            `json:"public_key"`
+	Keys      map[string][]byte `json:"keys"`
+}
+
+// e2eeAnnouncement publishes a member's public key to the group
+type e2eeAnnouncement struct {
+	PublicKey []byte `json:"public_key"`
+}
+
+// E2EEMessage is a received message after end-to-end decryption
+type E2EEMessage struct {
+	Message         // Msg and Type hold the decrypted content
+	Encrypted bool  // Whether the message was end-to-end encrypted
+	Err       error // Why an encrypted message could not be decrypted, Msg is then left as received
+}
+
+// E2EEGroup wraps a Group so that message contents are end-to-end encrypted.
+//
+// Messages are encrypted with a per-group AES-256-GCM key. Members publish their public
+// key with AnnouncePublicKey; RotateKey creates a new group key and sends it to every member
+// whose public key is known, sealed with the KeyExchange. Public keys are trusted on first use
+// and can also be registered from another source with AddPeer.
+//
+// Only Kick rotates the key when a member leaves. Removing members through the underlying
+// Group, for example with Group().Kick, BatchKick or Reconcile, or members leaving on their
+// own, does not: call RotateKey afterwards, or removed members can still read new messages.
+type E2EEGroup struct {
+	group *Group
+	kx    KeyExchange
+
+	mu      sync.Mutex
+	keys    map[uint32][]byte // Group keys by key ID
+	current uint32
+	hasKey  bool
+	ownsKey bool              // Whether the current key was created by us
+	peers   map[string][]byte // Public keys by username
+	keySent map[string]uint32 // Last key ID sent to each member
+}
+
+// NewE2EEGroup enables end-to-end encryption for group using kx for key distribution
+func NewE2EEGroup(group *Group, kx KeyExchange) *E2EEGroup {
+	return &E2EEGroup{
+		group:   group,
+		kx:      kx,
+		keys:    make(map[uint32][]byte),
+		peers:   make(map[string][]byte),
+		keySent: make(map[string]uint32),
+	}
+}
+
+// Group returns the underlying group
+func (e *E2EEGroup) Group() *Group {
+	return e.group
+}
+
+// AddPeer registers the public key of a member, for example from a directory the app trusts
+func (e *E2EEGroup) AddPeer(username string, publicKey []byte) {
+	e.mu.Lock()
+	e.peers[username] = append([]byte(nil), publicKey...)
+	e.mu.Unlock()
+}
+
+// AnnouncePublicKey publishes our public key to the group so members can send us group keys
+func (e *E2EEGroup) AnnouncePublicKey(ctx context.Context) error {
+	data, err := json.Marshal(e2eeAnnouncement{PublicKey: e.kx.PublicKey()})
+	if err != nil {
+		return err
+	}
+	_, err = e.group.SendMessage(ctx, Text, e2eeAnnouncePrefix+string(data))
+	return err
+}
+
+// RotateKey creates a new group key and sends it to all current members with a known public key.
+// The new key is used even if some members could not be reached; they are reported with
+// ErrMissingPublicKey and can be given the key once they announce their public key.
+func (e *E2EEGroup) RotateKey(ctx context.Context) error {
+	self, err := e.group.client.selfUsername(ctx)
+	if err != nil {
+		return err
+	}
+	members, err := e.group.GetMembers(ctx)
+	if err != nil {
+		return err
+	}
+
+	key := make([]byte, groupKeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return fmt.Errorf("failed to generate group key: %w", err)
+	}
+	var id [4]byte
+	if _, err := io.ReadFull(rand.Reader, id[:]); err != nil {
+		return fmt.Errorf("failed to generate key ID: %w", err)
+	}
+	keyID := binary.BigEndian.Uint32(id[:])
+
+	keyMsg := e2eeKeyMessage{KeyID: keyID, PublicKey: e.kx.PublicKey(), Keys: make(map[string][]byte)}
+	var missing []string
+	e.mu.Lock()
+	for _, m := range members {
+		publicKey, ok := e.peers[m.Name]
+		if m.Name == self {
+			publicKey, ok = e.kx.PublicKey(), true
+		}
+		if !ok {
+			missing = append(missing, m.Name)
+			continue
+		}
+		sealed, err := e.kx.SealKey(key, publicKey)
+		if err != nil {
+			e.mu.Unlock()
+			return fmt.Errorf("failed to seal group key for %s: %w", m.Name, err)
+		}
+		keyMsg.Keys[m.Name] = sealed
+	}
+	e.mu.Unlock()
+
+	if err := e.sendKeyMessage(ctx, &keyMsg); err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	e.keys[keyID] = key
+	e.current, e.hasKey, e.ownsKey = keyID, true, true
+	for username := range keyMsg.Keys {
+		e.keySent[username] = keyID
+	}
+	e.mu.Unlock()
+
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s", ErrMissingPublicKey, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// sendKeyMessage sends a key distribution message to the group
+func (e *E2EEGroup) sendKeyMessage(ctx context.Context, keyMsg *e2eeKeyMessage) error {
+	data, err := json.Marshal(keyMsg)
+	if err != nil {
+		return err
+	}
+	if _, err := e.group.SendMessage(ctx, Text, e2eeKeyPrefix+string(data)); err != nil {
+		return fmt.Errorf("failed to distribute group key: %w", err)
+	}
+	return nil
+}
+
+// Kick removes a member from the group and rotates the group key so they cannot read new messages.
+// Kicks through the underlying Group do not rotate the key, see E2EEGroup.
+func (e *E2EEGroup) Kick(ctx context.Context, username string) error {
+	if err := e.group.Kick(ctx, username); err != nil {
+		return err
+	}
+	e.mu.Lock()
+	delete(e.keySent, username)
+	e.mu.Unlock()
+	return e.RotateKey(ctx)
+}
+
+// SendText sends an encrypted text message
+func (e *E2EEGroup) SendText(ctx context.Context, message string) (*SentMessage, error) {
+	return e.SendMessage(ctx, Text, message)
+}
+
+// SendMessage encrypts content with the current group key and sends it. The receipt holds
+// the encrypted envelope as Msg. If there is no group key yet, a new one is created with RotateKey.
+func (e *E2EEGroup) SendMessage(ctx context.Context, msgType MessageType, content string) (*SentMessage, error) {
+	e.mu.Lock()
+	hasKey := e.hasKey
+	e.mu.Unlock()
+	if !hasKey {
+		if err := e.RotateKey(ctx); err != nil && !errors.Is(err, ErrMissingPublicKey) {
+			return nil, err
+		}
+	}
+
+	envelope, err := e.encrypt(msgType, content)
+	if err != nil {
+		return nil, err
+	}
+	return e.group.SendMessage(ctx, Text, envelope)
+}
+
+// encrypt builds the encrypted envelope for a message
+func (e *E2EEGroup) encrypt(msgType MessageType, content string) (string, error) {
+	e.mu.Lock()
+	keyID, key, ok := e.current, e.keys[e.current], e.hasKey
+	e.mu.Unlock()
+	if !ok {
+		return "", ErrNoGroupKey
+	}
+
+	plaintext, err := json.Marshal(e2eePayload{Type: msgType, Msg: content})
+	if err != nil {
+		return "", err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := seal(aead, plaintext, e.additionalData())
+	if err != nil {
+		return "", err
+	}
+
+	header := binary.BigEndian.AppendUint32(nil, keyID)
+	return e2eeMessagePrefix + base64.RawURLEncoding.EncodeToString(append(header, sealed...)), nil
+}
+
+// additionalData binds ciphertexts to the group so they cannot be replayed elsewhere
+func (e *E2EEGroup) additionalData() []byte {
+	return []byte(fmt.Sprintf("stealthim/e2ee/group/%d", e.group.GroupID))
+}
+
+// ReceiveMessages receives and decrypts group messages. Key distribution and public key
+// messages are processed internally and not delivered.
+func (e *E2EEGroup) ReceiveMessages(ctx context.Context, opts *ReceiveMessageOptions) (<-chan E2EEMessage, <-chan error) {
+	messages, errs := e.group.ReceiveMessages(ctx, opts)
+	out := make(chan E2EEMessage)
+
+	go func() {
+		defer close(out)
+		for msg := range messages {
+			decrypted, ok := e.HandleMessage(ctx, msg)
+			if !ok {
+				continue
+			}
+			select {
+			case out <- decrypted:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out, errs
+}
+
+// HandleMessage processes a message received through Group.ReceiveMessages. It returns
+// false for E2EE control messages, which update the key state and should not be shown.
+func (e *E2EEGroup) HandleMessage(ctx context.Context, msg Message) (E2EEMessage, bool) {
+	switch {
+	case strings.HasPrefix(msg.Msg, e2eeMessagePrefix):
+		return e.decrypt(msg), true
+	case strings.HasPrefix(msg.Msg, e2eeKeyPrefix):
+		if err := e.handleKeyMessage(ctx, msg); err != nil {
+			e.group.client.logger().LogAttrs(ctx, slog.LevelWarn, "e2ee key message rejected",
+				slog.Int64("group_id", e.group.GroupID),
+				slog.String("sender", msg.Username),
+				slog.Any("error", err),
+			)
+		}
+		return E2EEMessage{}, false
+	case strings.HasPrefix(msg.Msg, e2eeAnnouncePrefix):
+		if err := e.handleAnnouncement(ctx, msg); err != nil {
+			e.group.client.logger().LogAttrs(ctx, slog.LevelWarn, "e2ee public key rejected",
+				slog.Int64("group_id", e.group.GroupID),
+				slog.String("sender", msg.Username),
+				slog.Any("error", err),
+			)
+		}
+		return E2EEMessage{}, false
+	default:
+		return E2EEMessage{Message: msg}, true
+	}
+}
+
+// decrypt opens an encrypted message envelope
+func (e *E2EEGroup) decrypt(msg Message) E2EEMessage {
+	result := E2EEMessage{Message: msg, Encrypted: true}
+
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(msg.Msg, e2eeMessagePrefix))
+	if err != nil || len(data) < 4 {
+		result.Err = ErrDecryptionFailed
+		return result
+	}
+	keyID := binary.BigEndian.Uint32(data[:4])
+
+	e.mu.Lock()
+	key, ok := e.keys[keyID]
+	e.mu.Unlock()
+	if !ok {
+		result.Err = fmt.Errorf("%w: key %08x", ErrNoGroupKey, keyID)
+		return result
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	plaintext, err := open(aead, data[4:], e.additionalData())
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	var payload e2eePayload
+	if err := json.Unmarshal(plaintext, &payload); err != nil {
+		result.Err = fmt.Errorf("%w: %v", ErrDecryptionFailed, err)
+		return result
+	}
+
+	result.Msg = payload.Msg
+	result.Type = int(payload.Type)
+	return result
+}
+
+// handleKeyMessage stores a group key that was distributed to us
+func (e *E2EEGroup) handleKeyMessage(ctx context.Context, msg Message) error {
+	var keyMsg e2eeKeyMessage
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(msg.Msg, e2eeKeyPrefix)), &keyMsg); err != nil {
+		return err
+	}
+	self, err := e.group.client.selfUsername(ctx)
+	if err != nil {
+		return err
+	}
+	if err := e.trustPeer(self, msg.Username, keyMsg.PublicKey); err != nil {
+		return err
+	}
+
+	sealed, ok := keyMsg.Keys[self]
+	if !ok {
+		return nil // Not meant for us, for example sent before we joined
+	}
+	key, err := e.kx.OpenKey(sealed, keyMsg.PublicKey)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, known := e.keys[keyMsg.KeyID]; !known {
+		e.keys[keyMsg.KeyID] = key
+		e.current, e.hasKey = keyMsg.KeyID, true
+		e.ownsKey = msg.Username == self
+	}
+	return nil
+}
+
+// handleAnnouncement records a member's public key and shares the current key with them
+// if we created it and they are still a member of the group
+func (e *E2EEGroup) handleAnnouncement(ctx context.Context, msg Message) error {
+	var announcement e2eeAnnouncement
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(msg.Msg, e2eeAnnouncePrefix)), &announcement); err != nil {
+		return err
+	}
+	self, err := e.group.client.selfUsername(ctx)
+	if err != nil {
+		return err
+	}
+	if msg.Username == self {
+		return nil
+	}
+	if err := e.trustPeer(self, msg.Username, announcement.PublicKey); err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	share := e.ownsKey && e.keySent[msg.Username] != e.current
+	keyID, key := e.current, e.keys[e.current]
+	e.mu.Unlock()
+	if !share {
+		return nil
+	}
+
+	// Announcements are only trusted for key exchange, whether the sender may read the group
+	// is decided by the member list, e.g. a kicked member announcing a key gets nothing
+	members, err := e.group.GetMembers(ctx)
+	if err != nil {
+		return err
+	}
+	if !slices.ContainsFunc(members, func(m GroupMember) bool { return m.Name == msg.Username }) {
+		return fmt.Errorf("%s is not a member of the group", msg.Username)
+	}
+
+	sealed, err := e.kx.SealKey(key, announcement.PublicKey)
+	if err != nil {
+		return err
+	}
+	keyMsg := &e2eeKeyMessage{KeyID: keyID, PublicKey: e.kx.PublicKey(), Keys: map[string][]byte{msg.Username: sealed}}
+	if err := e.sendKeyMessage(ctx, keyMsg); err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	e.keySent[msg.Username] = keyID
+	e.mu.Unlock()
+	return nil
+}
+
+// trustPeer records the public key of username on first use and rejects a different key later.
+// self is our own username, whose key must always be our own public key.
+func (e *E2EEGroup) trustPeer(self, username string, publicKey []byte) error {
+	if username == self {
+		if !bytes.Equal(publicKey, e.kx.PublicKey()) {
+			return ErrPublicKeyMismatch
+		}
+		return nil
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	known, ok := e.peers[username]
+	if ok && !bytes.Equal(known, publicKey) {
+		return fmt.Errorf("%w: %s", ErrPublicKeyMismatch, username)
+	}
+	if !ok {
+		e.peers[username] = append([]byte(nil), publicKey...)
+	}
+	return nil
+}
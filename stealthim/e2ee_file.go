@@ -0,0 +1,286 @@
+package stealthim
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// ErrNotEncryptedFile is returned when a message does not describe an encrypted file
+var ErrNotEncryptedFile = errors.New("not an encrypted file message")
+
+// encryptedFileName is the name the server sees for encrypted uploads, the real name
+// only travels inside the encrypted File message
+const encryptedFileName = "encrypted"
+
+// EncryptedFile describes an end-to-end encrypted attachment. It is sent as the content of an
+// encrypted File message, so the key never reaches the server in the clear.
+//
+// The file is encrypted with AES-256-GCM in chunks of ChunkSize bytes. Each encrypted chunk,
+// including its authentication tag, fills exactly one upload block, so blocks can be
+// downloaded and decrypted independently.
+type EncryptedFile struct {
+	Hash      string `json:"hash"`       // Hash of the encrypted file on the server
+	Name      string `json:"name"`       // Original file name
+	Size      int64  `json:"size"`       // Size of the decrypted file
+	ChunkSize int64  `json:"chunk_size"` // Plaintext bytes per chunk
+	Key       []byte `json:"key"`
+	Nonce     []byte `json:"nonce"` // Base nonce, combined with the chunk index for each chunk
+}
+
+// SendFile encrypts a file with a new random key, uploads it and sends the key and the
+// original file name in an encrypted File message
+func (e *E2EEGroup) SendFile(ctx context.Context, filename, filepath string) (*EncryptedFile, error) {
+	blockSize, _, err := e.group.client.uploadLimits(ctx)
+	if err != nil {
+		return nil, err
+	}
+	overhead := int64(16) // AES-GCM tag
+	if blockSize <= overhead {
+		return nil, fmt.Errorf("block size %d is too small for encrypted uploads", blockSize)
+	}
+
+	file := &EncryptedFile{
+		Name:      filename,
+		ChunkSize: blockSize - overhead,
+		Key:       make([]byte, groupKeySize),
+		Nonce:     make([]byte, 12),
+	}
+	if _, err := io.ReadFull(rand.Reader, file.Key); err != nil {
+		return nil, fmt.Errorf("failed to generate file key: %w", err)
+	}
+	if _, err := io.ReadFull(rand.Reader, file.Nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate file nonce: %w", err)
+	}
+
+	src, err := os.Open(filepath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file info: %w", err)
+	}
+	file.Size = info.Size()
+
+	tmp, err := os.CreateTemp("", "stealthim-e2ee-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if err := file.encrypt(src, tmp); err != nil {
+		return nil, err
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write temporary file: %w", err)
+	}
+
+	file.Hash, err = e.group.sendFile(ctx, encryptedFileName, tmp.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := json.Marshal(file)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := e.SendMessage(ctx, File, string(payload)); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// ParseEncryptedFile extracts the attachment description from a decrypted File message
+func ParseEncryptedFile(msg E2EEMessage) (*EncryptedFile, error) {
+	if !msg.Encrypted || msg.Err != nil || MessageType(msg.Type) != File {
+		return nil, ErrNotEncryptedFile
+	}
+	var file EncryptedFile
+	if err := json.Unmarshal([]byte(msg.Msg), &file); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotEncryptedFile, err)
+	}
+	if file.Hash == "" || len(file.Key) != groupKeySize || len(file.Nonce) != 12 || file.ChunkSize <= 0 {
+		return nil, ErrNotEncryptedFile
+	}
+	return &file, nil
+}
+
+// ReadEncryptedFileRange downloads and decrypts length bytes of an end-to-end encrypted
+// attachment, starting at offset in the decrypted file. Only the blocks holding the range
+// are requested.
+func (c *Client) ReadEncryptedFileRange(ctx context.Context, file *EncryptedFile, offset, length int64) ([]byte, error) {
+	if offset < 0 || length < 0 || offset+length > file.Size {
+		return nil, fmt.Errorf("range %d+%d is outside the file of %d bytes", offset, length, file.Size)
+	}
+	if length == 0 {
+		return []byte{}, nil
+	}
+
+	first := uint32(offset / file.ChunkSize)
+	last := uint32((offset + length - 1) / file.ChunkSize)
+	data, err := c.downloadChunks(ctx, file, first, last)
+	if err != nil {
+		return nil, err
+	}
+	start := offset - int64(first)*file.ChunkSize
+	return data[start : start+length], nil
+}
+
+// downloadEncryptedFile downloads and decrypts an attachment to outputPath, splitting the
+// chunks into up to threads ranges that are downloaded in parallel.
+// The output file is removed if any block is missing or fails to decrypt.
+func (c *Client) downloadEncryptedFile(ctx context.Context, file *EncryptedFile, outputPath string, threads int) (err error) {
+	chunks := file.chunkCount()
+	// Servers without range support would send the whole file to every thread
+	if info := c.cachedServerInfo(); info != nil && !info.Features.RangeSupport {
+		threads = 1
+	}
+	threads = max(1, min(threads, int(chunks)))
+
+	outFile, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	defer func() {
+		outFile.Close()
+		if err != nil {
+			os.Remove(outputPath)
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	errs := make([]error, threads)
+	perThread := (chunks + uint32(threads) - 1) / uint32(threads)
+	for i := 0; i < threads; i++ {
+		first := uint32(i) * perThread
+		last := min(first+perThread, chunks) - 1
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			data, err := c.downloadChunks(ctx, file, first, last)
+			errs[i] = err
+			if err == nil {
+				if _, err := outFile.WriteAt(data, int64(first)*file.ChunkSize); err != nil {
+					errs[i] = fmt.Errorf("failed to write chunks %d-%d to file: %w", first, last, err)
+				}
+			}
+			if errs[i] != nil {
+				cancel()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// Report the error that cancelled the other threads rather than the cancellation
+	for _, err := range errs {
+		if err != nil && !errors.Is(err, context.Canceled) {
+			return err
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// downloadChunks downloads the blocks holding chunks first to last and returns their
+// decrypted content. A single request covering the whole file is sent without a range.
+func (c *Client) downloadChunks(ctx context.Context, file *EncryptedFile, first, last uint32) ([]byte, error) {
+	rangeHeader := ""
+	if first != 0 || last != file.chunkCount()-1 {
+		blockSize := file.ChunkSize + 16 // AES-GCM tag
+		rangeHeader = fmt.Sprintf("bytes=%d-%d", int64(first)*blockSize, int64(last+1)*blockSize-1)
+	}
+	blocks, err := c.downloadFileRange(ctx, file.Hash, rangeHeader)
+	if err != nil {
+		return nil, err
+	}
+
+	blocksMap := make(map[uint32][]byte, len(blocks))
+	for _, block := range blocks {
+		blocksMap[block.BlockID] = block.Data
+	}
+	var plaintext []byte
+	for i := first; i <= last; i++ {
+		block, ok := blocksMap[i]
+		if !ok {
+			return nil, fmt.Errorf("%w: chunk %d is missing", ErrDecryptionFailed, i)
+		}
+		chunk, err := file.decryptChunk(i, block)
+		if err != nil {
+			return nil, err
+		}
+		plaintext = append(plaintext, chunk...)
+	}
+	return plaintext, nil
+}
+
+// chunkCount returns the number of encrypted chunks. Empty files still get one chunk,
+// so a missing file body is detected.
+func (f *EncryptedFile) chunkCount() uint32 {
+	if f.Size == 0 {
+		return 1
+	}
+	return uint32((f.Size + f.ChunkSize - 1) / f.ChunkSize)
+}
+
+// encrypt reads the plaintext from src and writes the encrypted chunks to dst
+func (f *EncryptedFile) encrypt(src io.Reader, dst io.Writer) error {
+	aead, err := newAEAD(f.Key)
+	if err != nil {
+		return err
+	}
+
+	chunks := f.chunkCount()
+	buffer := make([]byte, f.ChunkSize)
+	for i := uint32(0); i < chunks; i++ {
+		n, err := io.ReadFull(src, buffer)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return fmt.Errorf("failed to read file: %w", err)
+		}
+		ciphertext := aead.Seal(nil, f.chunkNonce(i), buffer[:n], chunkAAD(i, i == chunks-1))
+		if _, err := dst.Write(ciphertext); err != nil {
+			return fmt.Errorf("failed to write encrypted chunk: %w", err)
+		}
+	}
+	return nil
+}
+
+// decryptChunk decrypts the chunk stored in the block with the given ID
+func (f *EncryptedFile) decryptChunk(index uint32, data []byte) ([]byte, error) {
+	aead, err := newAEAD(f.Key)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, f.chunkNonce(index), data, chunkAAD(index, index == f.chunkCount()-1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: chunk %d", ErrDecryptionFailed, index)
+	}
+	return plaintext, nil
+}
+
+// chunkNonce derives the nonce of a chunk by XORing its index into the end of the base nonce
+func (f *EncryptedFile) chunkNonce(index uint32) []byte {
+	nonce := append([]byte(nil), f.Nonce...)
+	n := len(nonce)
+	binary.BigEndian.PutUint32(nonce[n-4:], binary.BigEndian.Uint32(nonce[n-4:])^index)
+	return nonce
+}
+
+// chunkAAD authenticates the position of a chunk, so chunks cannot be reordered or the file truncated
+func chunkAAD(index uint32, last bool) []byte {
+	aad := binary.BigEndian.AppendUint32([]byte("stealthim/e2ee/file/"), index)
+	if last {
+		return append(aad, 1)
+	}
+	return append(aad, 0)
+}
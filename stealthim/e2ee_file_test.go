@@ -0,0 +1,139 @@
+package stealthim
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// TestE2EEFile tests that encrypted attachments can be sent, received and downloaded
+func TestE2EEFile(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.blockSize = 64
+	aliceUser := fs.login(t, "alice")
+	bobUser := fs.login(t, "bob")
+	group := fs.createGroup(t, aliceUser, "Secret", "bob")
+
+	alice := newE2EEMember(t, aliceUser, group)
+	bob := newE2EEMember(t, bobUser, group)
+	ctx := context.Background()
+
+	if err := bob.AnnouncePublicKey(ctx); err != nil {
+		t.Fatalf("AnnouncePublicKey failed: %v", err)
+	}
+	alice.HandleMessage(ctx, receiveRaw(t, alice.Group(), 1)[0])
+
+	dir := t.TempDir()
+	content := bytes.Repeat([]byte("top secret plans. "), 12) // 216 bytes, 5 chunks of 48
+	source := filepath.Join(dir, "plans.txt")
+	if err := os.WriteFile(source, content, 0o600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	sent, err := alice.SendFile(ctx, "plans.txt", source)
+	if err != nil {
+		t.Fatalf("SendFile failed: %v", err)
+	}
+	if sent.ChunkSize != 48 || sent.chunkCount() != 5 {
+		t.Errorf("Expected 5 chunks of 48 bytes, got %+v", sent)
+	}
+
+	fs.mu.Lock()
+	blocks := fs.files[sent.Hash]
+	fs.mu.Unlock()
+	if len(blocks) != 5 || len(blocks[0]) != 64 {
+		t.Fatalf("Expected 5 blocks of 64 bytes on the server, got %d", len(blocks))
+	}
+	for _, block := range blocks {
+		if bytes.Contains(block, []byte("secret")) {
+			t.Fatal("Server can read the file content")
+		}
+	}
+
+	// bob gets the key from the File message
+	var received *EncryptedFile
+	for _, msg := range receiveRaw(t, bob.Group(), 3) {
+		if decrypted, ok := bob.HandleMessage(ctx, msg); ok {
+			if received, err = ParseEncryptedFile(decrypted); err != nil {
+				t.Fatalf("ParseEncryptedFile failed: %v", err)
+			}
+		}
+	}
+	if received == nil || received.Name != "plans.txt" || received.Hash != sent.Hash {
+		t.Fatalf("Unexpected attachment: %+v", received)
+	}
+
+	output := filepath.Join(dir, "downloaded.txt")
+	for _, threads := range []int{1, 3, 8} {
+		if err := bobUser.client.DownloadFile(ctx, received.Hash, output, threads, WithDecryption(received)); err != nil {
+			t.Fatalf("DownloadFile with %d threads failed: %v", threads, err)
+		}
+		downloaded, err := os.ReadFile(output)
+		if err != nil || !bytes.Equal(downloaded, content) {
+			t.Errorf("Downloaded content with %d threads does not match: %q (%v)", threads, downloaded, err)
+		}
+	}
+	fs.mu.Lock()
+	ranges := fs.ranges
+	fs.ranges = nil
+	fs.mu.Unlock()
+	// One request without a range, then 3 ranges and 5 ranges of single chunks, in any order
+	if len(ranges) != 8 || !slices.Contains(ranges, "bytes=0-127") || !slices.Contains(ranges, "bytes=256-319") {
+		t.Errorf("Expected parallel ranged requests, got %v", ranges)
+	}
+	if err := bobUser.client.DownloadFile(ctx, "other", output, 1, WithDecryption(received)); !errors.Is(err, ErrNotEncryptedFile) {
+		t.Errorf("Expected ErrNotEncryptedFile for a mismatched hash, got %v", err)
+	}
+
+	// A range in the middle only fetches the chunks holding it
+	part, err := bobUser.client.ReadEncryptedFileRange(ctx, received, 50, 100)
+	if err != nil || !bytes.Equal(part, content[50:150]) {
+		t.Errorf("Range content does not match: %q (%v)", part, err)
+	}
+	fs.mu.Lock()
+	ranges = fs.ranges
+	fs.mu.Unlock()
+	if len(ranges) != 1 || ranges[0] != "bytes=64-255" {
+		t.Errorf("Expected a request for chunks 1 to 3, got %v", ranges)
+	}
+	if _, err := bobUser.client.ReadEncryptedFileRange(ctx, received, 200, 17); err == nil {
+		t.Error("Expected a range past the end of the file to fail")
+	}
+
+	// Blocks decrypt independently, in any order
+	for _, i := range []uint32{3, 0, 4} {
+		chunk, err := received.decryptChunk(i, blocks[i])
+		if err != nil || !bytes.Equal(chunk, content[int64(i)*48:min(int64(i+1)*48, int64(len(content)))]) {
+			t.Errorf("Chunk %d did not decrypt on its own: %v", i, err)
+		}
+	}
+
+	// Dropping the last block is detected and leaves no partial output
+	fs.mu.Lock()
+	fs.files[sent.Hash] = blocks[:4]
+	fs.mu.Unlock()
+	for _, threads := range []int{1, 3} {
+		if err := bobUser.client.DownloadFile(ctx, received.Hash, output, threads, WithDecryption(received)); !errors.Is(err, ErrDecryptionFailed) {
+			t.Errorf("Expected a truncated download with %d threads to fail, got %v", threads, err)
+		}
+		if _, err := os.Stat(output); !os.IsNotExist(err) {
+			t.Errorf("Expected the partial output with %d threads to be removed", threads)
+		}
+	}
+}
+
+// TestParseEncryptedFile tests that only encrypted File messages are accepted
+func TestParseEncryptedFile(t *testing.T) {
+	plain := E2EEMessage{Message: Message{Type: int(File), Msg: `{"hash":"abc"}`}}
+	if _, err := ParseEncryptedFile(plain); !errors.Is(err, ErrNotEncryptedFile) {
+		t.Errorf("Expected ErrNotEncryptedFile for an unencrypted message, got %v", err)
+	}
+	text := E2EEMessage{Message: Message{Type: int(Text), Msg: "hi"}, Encrypted: true}
+	if _, err := ParseEncryptedFile(text); !errors.Is(err, ErrNotEncryptedFile) {
+		t.Errorf("Expected ErrNotEncryptedFile for a text message, got %v", err)
+	}
+}
@@ -0,0 +1,237 @@
+package stealthim
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// receiveRaw reads the first n messages of a group from the server
+func receiveRaw(t *testing.T, group *Group, n int) []Message {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	messages, errs := group.ReceiveMessages(ctx, DefaultReceiveMessageOptions())
+	var received []Message
+	for len(received) < n {
+		select {
+		case msg, ok := <-messages:
+			if !ok {
+				t.Fatalf("Stream ended after %d of %d messages", len(received), n)
+			}
+			received = append(received, msg)
+		case err := <-errs:
+			t.Fatalf("Receive failed: %v", err)
+		}
+	}
+	return received
+}
+
+// newE2EEMember creates an E2EE view of group for user
+func newE2EEMember(t *testing.T, user *User, group *Group) *E2EEGroup {
+	t.Helper()
+	kx, err := NewX25519KeyExchange()
+	if err != nil {
+		t.Fatalf("Failed to create key exchange: %v", err)
+	}
+	return NewE2EEGroup(&Group{client: user.client, GroupID: group.GroupID}, kx)
+}
+
+// TestX25519KeyExchange tests sealing and opening group keys
+func TestX25519KeyExchange(t *testing.T) {
+	alice, _ := NewX25519KeyExchange()
+	bob, _ := NewX25519KeyExchange()
+	mallory, _ := NewX25519KeyExchange()
+
+	key := bytes.Repeat([]byte{7}, groupKeySize)
+	sealed, err := alice.SealKey(key, bob.PublicKey())
+	if err != nil {
+		t.Fatalf("SealKey failed: %v", err)
+	}
+	opened, err := bob.OpenKey(sealed, alice.PublicKey())
+	if err != nil || !bytes.Equal(opened, key) {
+		t.Fatalf("Expected bob to open the key, got %v", err)
+	}
+	if _, err := mallory.OpenKey(sealed, alice.PublicKey()); !errors.Is(err, ErrDecryptionFailed) {
+		t.Errorf("Expected mallory to fail, got %v", err)
+	}
+
+	restored, err := LoadX25519KeyExchange(bob.PrivateKey())
+	if err != nil || !bytes.Equal(restored.PublicKey(), bob.PublicKey()) {
+		t.Errorf("Expected the restored key pair to match, got %v", err)
+	}
+}
+
+// TestE2EEGroup tests key distribution, encrypted messaging and key rotation on kick
+func TestE2EEGroup(t *testing.T) {
+	fs := newFakeServer(t)
+	aliceUser := fs.login(t, "alice")
+	bobUser := fs.login(t, "bob")
+	carolUser := fs.login(t, "carol")
+	group := fs.createGroup(t, aliceUser, "Secret", "bob", "carol")
+
+	alice := newE2EEMember(t, aliceUser, group)
+	bob := newE2EEMember(t, bobUser, group)
+	carol := newE2EEMember(t, carolUser, group)
+	ctx := context.Background()
+
+	if err := bob.AnnouncePublicKey(ctx); err != nil {
+		t.Fatalf("AnnouncePublicKey failed: %v", err)
+	}
+	if err := carol.AnnouncePublicKey(ctx); err != nil {
+		t.Fatalf("AnnouncePublicKey failed: %v", err)
+	}
+	for _, msg := range receiveRaw(t, alice.Group(), 2) {
+		if _, ok := alice.HandleMessage(ctx, msg); ok {
+			t.Errorf("Expected the announcement to be handled internally: %+v", msg)
+		}
+	}
+
+	if err := alice.RotateKey(ctx); err != nil {
+		t.Fatalf("RotateKey failed: %v", err)
+	}
+	if _, err := alice.SendText(ctx, "launch at dawn"); err != nil {
+		t.Fatalf("SendText failed: %v", err)
+	}
+
+	fs.mu.Lock()
+	stored := fs.groups[group.GroupID].messages[3].Msg
+	fs.mu.Unlock()
+	if strings.Contains(stored, "dawn") {
+		t.Fatalf("Server can read the message: %q", stored)
+	}
+
+	for _, member := range []*E2EEGroup{bob, carol} {
+		raw := receiveRaw(t, member.Group(), 4)
+		for _, msg := range raw[:3] {
+			member.HandleMessage(ctx, msg)
+		}
+		decrypted, ok := member.HandleMessage(ctx, raw[3])
+		if !ok || decrypted.Err != nil || !decrypted.Encrypted || decrypted.Msg != "launch at dawn" || decrypted.Type != int(Text) {
+			t.Errorf("Unexpected decrypted message: %+v", decrypted)
+		}
+	}
+
+	// Kicking carol rotates the key, so she cannot read later messages
+	if err := alice.Kick(ctx, "carol"); err != nil {
+		t.Fatalf("Kick failed: %v", err)
+	}
+	if _, err := alice.SendText(ctx, "carol is gone"); err != nil {
+		t.Fatalf("SendText failed: %v", err)
+	}
+	raw := receiveRaw(t, bob.Group(), 6)
+	for _, msg := range raw[4:5] {
+		bob.HandleMessage(ctx, msg)
+	}
+	if decrypted, _ := bob.HandleMessage(ctx, raw[5]); decrypted.Err != nil || decrypted.Msg != "carol is gone" {
+		t.Errorf("Expected bob to read the message after rotation, got %+v", decrypted)
+	}
+	carol.HandleMessage(ctx, raw[4])
+	if decrypted, _ := carol.HandleMessage(ctx, raw[5]); !errors.Is(decrypted.Err, ErrNoGroupKey) {
+		t.Errorf("Expected carol to be locked out, got %+v", decrypted)
+	}
+}
+
+// TestE2EEGroupLateJoiner tests that the key owner shares the key when a member announces later
+func TestE2EEGroupLateJoiner(t *testing.T) {
+	fs := newFakeServer(t)
+	aliceUser := fs.login(t, "alice")
+	bobUser := fs.login(t, "bob")
+	group := fs.createGroup(t, aliceUser, "Secret", "bob")
+
+	alice := newE2EEMember(t, aliceUser, group)
+	bob := newE2EEMember(t, bobUser, group)
+	ctx := context.Background()
+
+	// bob's public key is unknown when the key is created
+	if _, err := alice.SendText(ctx, "hello"); err != nil {
+		t.Fatalf("SendText failed: %v", err)
+	}
+	if err := bob.AnnouncePublicKey(ctx); err != nil {
+		t.Fatalf("AnnouncePublicKey failed: %v", err)
+	}
+	for _, msg := range receiveRaw(t, alice.Group(), 3) {
+		alice.HandleMessage(ctx, msg)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	messages, _ := bob.ReceiveMessages(ctx, DefaultReceiveMessageOptions())
+	for msg := range messages {
+		if msg.Err == nil && msg.Msg == "hello" {
+			cancel()
+			return
+		}
+	}
+	t.Error("Expected bob to decrypt the message once alice shared the key")
+}
+
+// TestE2EEGroupNonMemberAnnouncement tests that the group key is not shared with a non-member
+func TestE2EEGroupNonMemberAnnouncement(t *testing.T) {
+	fs := newFakeServer(t)
+	aliceUser := fs.login(t, "alice")
+	malloryUser := fs.login(t, "mallory")
+	group := fs.createGroup(t, aliceUser, "Secret")
+
+	alice := newE2EEMember(t, aliceUser, group)
+	mallory := newE2EEMember(t, malloryUser, group)
+	ctx := context.Background()
+	if err := alice.RotateKey(ctx); err != nil {
+		t.Fatalf("RotateKey failed: %v", err)
+	}
+
+	// A server could relay an announcement from a user outside the group
+	data, _ := json.Marshal(e2eeAnnouncement{PublicKey: mallory.kx.PublicKey()})
+	requests := countRequests(aliceUser.client)
+	alice.HandleMessage(ctx, Message{Username: "mallory", Msg: e2eeAnnouncePrefix + string(data)})
+	if *requests != 1 {
+		t.Errorf("Expected only the member list to be requested, got %d requests", *requests)
+	}
+	alice.mu.Lock()
+	_, sent := alice.keySent["mallory"]
+	alice.mu.Unlock()
+	if sent {
+		t.Error("Expected the group key not to be shared with a non-member")
+	}
+}
+
+// TestE2EEGroupRestoredSession tests key exchange for a client restored from a session
+func TestE2EEGroupRestoredSession(t *testing.T) {
+	fs := newFakeServer(t)
+	aliceUser := fs.login(t, "alice")
+	bobUser := fs.login(t, "bob")
+	group := fs.createGroup(t, aliceUser, "Secret", "bob")
+
+	alice := newE2EEMember(t, aliceUser, group)
+	restored := &User{client: NewClientWithSession(fs.URL, bobUser.client.Session)}
+	bob := newE2EEMember(t, restored, group)
+	ctx := context.Background()
+
+	if err := bob.AnnouncePublicKey(ctx); err != nil {
+		t.Fatalf("AnnouncePublicKey failed: %v", err)
+	}
+	for _, msg := range receiveRaw(t, alice.Group(), 1) {
+		alice.HandleMessage(ctx, msg)
+	}
+	if _, err := alice.SendText(ctx, "hello"); err != nil {
+		t.Fatalf("SendText failed: %v", err)
+	}
+
+	// bob's own announcement must be recognized as his, and the key sealed for him opened
+	for _, msg := range receiveRaw(t, bob.Group(), 3) {
+		decrypted, ok := bob.HandleMessage(ctx, msg)
+		if !ok || !decrypted.Encrypted {
+			continue
+		}
+		if decrypted.Err != nil || decrypted.Msg != "hello" {
+			t.Errorf("Expected bob to decrypt the message, got %q (%v)", decrypted.Msg, decrypted.Err)
+		}
+		return
+	}
+	t.Error("Expected an encrypted message")
+}
@@ -17,7 +17,7 @@ func (e *StealthError) Error() string {
 
 // IsSuccess checks if the result is successful
 func (r *Result) IsSuccess() bool {
-	return r.Code == 800
+	return r.Code == CodeSuccess
 }
 
 // ToError converts a Result to an error if not successful
@@ -36,4 +36,15 @@ var (
 	ErrPermissionDenied  = errors.New("permission denied")
 	ErrGroupNotFound     = errors.New("group not found")
 	ErrFileNotFound      = errors.New("file not found")
-)
\ No newline at end of file
+)
+
+// CodeSuccess is the result code of a successful request. Other codes are passed on in
+// StealthError.Code without interpretation, since the server does not document them.
+const CodeSuccess = 800
+
+// isServerRejection reports whether err is a failed result from the server, as opposed to
+// a transport or client-side error
+func isServerRejection(err error) bool {
+	var stealthErr *StealthError
+	return errors.As(err, &stealthErr)
+}
@@ -1,6 +1,8 @@
 package stealthim
 
 import (
+	"errors"
+	"fmt"
 	"testing"
 )
 
@@ -38,4 +40,15 @@ func TestCommonErrors(t *testing.T) {
 	if ErrFileNotFound.Error() != "file not found" {
 		t.Error("ErrFileNotFound has incorrect message")
 	}
-}
\ No newline at end of file
+}
+
+// TestIsServerRejection tests that only failed results count as server rejections
+func TestIsServerRejection(t *testing.T) {
+	err := fmt.Errorf("kick request failed: %w", &StealthError{Code: 900, Msg: "server error"})
+	if !isServerRejection(err) {
+		t.Error("Expected a wrapped StealthError to be a server rejection")
+	}
+	if isServerRejection(errors.New("connection refused")) || isServerRejection(ErrPermissionDenied) {
+		t.Error("Expected transport and client-side errors not to be server rejections")
+	}
+}
@@ -0,0 +1,286 @@
+package stealthim
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// EndpointState describes the health of a server endpoint
+type EndpointState struct {
+	URL       string
+	Healthy   bool
+	Current   bool          // Whether requests are currently routed to this endpoint
+	LastCheck time.Time     // Time of the last health check or request outcome
+	Latency   time.Duration // Latency of the last successful health check
+	LastError error
+}
+
+// EndpointPool holds a list of server endpoints and routes requests to a healthy one.
+// All endpoints start out healthy; failed requests and health checks mark them unhealthy.
+type EndpointPool struct {
+	mu        sync.RWMutex
+	endpoints []EndpointState
+	current   int
+}
+
+// NewEndpointPool creates an endpoint pool from a list of base URLs.
+// The first URL is used until it becomes unhealthy.
+func NewEndpointPool(baseURLs ...string) *EndpointPool {
+	p := &EndpointPool{
+		endpoints: make([]EndpointState, len(baseURLs)),
+	}
+	for i, baseURL := range baseURLs {
+		p.endpoints[i] = EndpointState{URL: baseURL, Healthy: true}
+	}
+	return p
+}
+
+// Current returns the base URL requests are currently routed to
+func (p *EndpointPool) Current() string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if len(p.endpoints) == 0 {
+		return ""
+	}
+	return p.endpoints[p.current].URL
+}
+
+// States returns a snapshot of the health state of every endpoint
+func (p *EndpointPool) States() []EndpointState {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	states := make([]EndpointState, len(p.endpoints))
+	copy(states, p.endpoints)
+	if len(states) > 0 {
+		states[p.current].Current = true
+	}
+	return states
+}
+
+// candidates returns endpoints in the order they should be tried:
+// the current endpoint if healthy, then other healthy endpoints, then unhealthy ones as a last resort
+func (p *EndpointPool) candidates() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var healthy, unhealthy []string
+	for offset := range p.endpoints {
+		ep := p.endpoints[(p.current+offset)%len(p.endpoints)]
+		if ep.Healthy {
+			healthy = append(healthy, ep.URL)
+		} else {
+			unhealthy = append(unhealthy, ep.URL)
+		}
+	}
+	return append(healthy, unhealthy...)
+}
+
+// markHealthy records a successful request or health check and routes requests to the endpoint
+// if the current endpoint is unhealthy
+func (p *EndpointPool) markHealthy(baseURL string, latency time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i := range p.endpoints {
+		if p.endpoints[i].URL != baseURL {
+			continue
+		}
+		p.endpoints[i].Healthy = true
+		p.endpoints[i].LastCheck = time.Now()
+		p.endpoints[i].LastError = nil
+		if latency > 0 {
+			p.endpoints[i].Latency = latency
+		}
+		if !p.endpoints[p.current].Healthy {
+			p.current = i
+		}
+		return
+	}
+}
+
+// markUnhealthy records a failed request or health check and moves
+// the current endpoint to the next healthy one
+func (p *EndpointPool) markUnhealthy(baseURL string, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i := range p.endpoints {
+		if p.endpoints[i].URL != baseURL {
+			continue
+		}
+		p.endpoints[i].Healthy = false
+		p.endpoints[i].LastCheck = time.Now()
+		p.endpoints[i].LastError = err
+		if i == p.current {
+			for offset := 1; offset < len(p.endpoints); offset++ {
+				next := (i + offset) % len(p.endpoints)
+				if p.endpoints[next].Healthy {
+					p.current = next
+					break
+				}
+			}
+		}
+		return
+	}
+}
+
+// transportError is a failure to exchange a request with an endpoint, as opposed to errors
+// building the request or interpreting the reply. Only transport errors mark an endpoint unhealthy.
+type transportError struct {
+	op  string
+	err error
+}
+
+func (e *transportError) Error() string {
+	return e.op + ": " + e.err.Error()
+}
+
+func (e *transportError) Unwrap() error {
+	return e.err
+}
+
+// idempotentMethods are the methods that are safe to repeat on another endpoint
+// even if the failed endpoint may have processed the request
+var idempotentMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPut:     true,
+	http.MethodDelete:  true,
+	http.MethodOptions: true,
+}
+
+// requestNotSent reports whether err happened before the request reached the server,
+// so that repeating it elsewhere cannot apply it twice
+func requestNotSent(err error) bool {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) || errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// endpointKey is the context key holding the base URL selected for a request
+type endpointKey struct{}
+
+// baseURL returns the base URL a request should be sent to
+func (c *Client) baseURL(ctx context.Context) string {
+	if baseURL, ok := ctx.Value(endpointKey{}).(string); ok {
+		return baseURL
+	}
+	if c.Endpoints != nil {
+		return c.Endpoints.Current()
+	}
+	return c.BaseURL
+}
+
+// withFailover wraps an invoker so that connection failures are retried on the next healthy endpoint.
+// Requests that may have reached the server are only repeated for idempotent methods, so a
+// message is never posted twice. The session is kept on the client, so it is reused on every endpoint.
+func (c *Client) withFailover(final Invoker) Invoker {
+	if c.Endpoints == nil {
+		return final
+	}
+	return func(ctx context.Context, call *Call) (*Reply, error) {
+		candidates := c.Endpoints.candidates()
+		if len(candidates) == 0 {
+			return final(ctx, call)
+		}
+
+		var lastErr error
+		for _, baseURL := range candidates {
+			reply, err := final(context.WithValue(ctx, endpointKey{}, baseURL), call)
+			if err == nil {
+				c.Endpoints.markHealthy(baseURL, 0)
+				return reply, nil
+			}
+			var transportErr *transportError
+			if ctx.Err() != nil || !errors.As(err, &transportErr) {
+				return nil, err
+			}
+
+			c.Endpoints.markUnhealthy(baseURL, err)
+			if !requestNotSent(err) && !idempotentMethods[call.Method] {
+				return nil, err
+			}
+			c.logger().LogAttrs(ctx, slog.LevelWarn, "endpoint failed, trying next",
+				slog.String("endpoint_url", baseURL),
+				slog.String("method", call.Method),
+				slog.String("endpoint", call.Endpoint),
+				slog.Any("error", err),
+			)
+			lastErr = err
+		}
+		return nil, lastErr
+	}
+}
+
+// NewMultiServer creates a server instance that routes requests to several regional endpoints
+// and fails over to a healthy one when an endpoint cannot be reached
+func NewMultiServer(baseURLs ...string) *Server {
+	client := NewClient("")
+	client.Endpoints = NewEndpointPool(baseURLs...)
+	if len(baseURLs) > 0 {
+		client.BaseURL = baseURLs[0]
+	}
+	return &Server{
+		client: client,
+	}
+}
+
+// Endpoints returns the endpoint pool of a multi-server instance, or nil for a single server
+func (s *Server) Endpoints() *EndpointPool {
+	return s.client.Endpoints
+}
+
+// CheckEndpoints pings every endpoint of a multi-server instance and updates its health state
+func (s *Server) CheckEndpoints(ctx context.Context) []EndpointState {
+	pool := s.client.Endpoints
+	if pool == nil {
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	for _, state := range pool.States() {
+		wg.Add(1)
+		go func(baseURL string) {
+			defer wg.Done()
+			client := s.client.clone()
+			client.BaseURL, client.Endpoints = baseURL, nil
+			probe := NewServerWithClient(client)
+			start := time.Now()
+			if err := probe.Ping(ctx); err != nil {
+				pool.markUnhealthy(baseURL, err)
+				s.client.logger().LogAttrs(ctx, slog.LevelWarn, "endpoint health check failed",
+					slog.String("endpoint_url", baseURL),
+					slog.Any("error", err),
+				)
+				return
+			}
+			pool.markHealthy(baseURL, time.Since(start))
+		}(state.URL)
+	}
+	wg.Wait()
+
+	return pool.States()
+}
+
+// StartHealthChecks checks the endpoints every interval until ctx is cancelled
+func (s *Server) StartHealthChecks(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			s.CheckEndpoints(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
@@ -0,0 +1,148 @@
+package stealthim
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// TestMultiServerFailover tests that requests fail over to a healthy endpoint and keep the session
+func TestMultiServerFailover(t *testing.T) {
+	down := httptest.NewServer(http.NotFoundHandler())
+	downURL := down.URL
+	down.Close()
+
+	var authorization string
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		w.Write([]byte(`{"result":{"code":800,"msg":""},"groups":[1]}`))
+	}))
+	defer up.Close()
+
+	server := NewMultiServer(downURL, up.URL)
+	server.client.Session = "test-session"
+	user := &User{client: server.client}
+
+	if _, err := user.GetGroups(context.Background()); err != nil {
+		t.Fatalf("Expected failover to succeed, got %v", err)
+	}
+	if authorization != "Bearer test-session" {
+		t.Errorf("Expected session to be kept after failover, got %q", authorization)
+	}
+	if server.Endpoints().Current() != up.URL {
+		t.Errorf("Expected current endpoint %s, got %s", up.URL, server.Endpoints().Current())
+	}
+
+	states := server.Endpoints().States()
+	if states[0].Healthy || states[0].LastError == nil {
+		t.Error("Expected unreachable endpoint to be unhealthy")
+	}
+	if !states[1].Healthy || !states[1].Current {
+		t.Error("Expected reachable endpoint to be healthy and current")
+	}
+}
+
+// TestMultiServerCheckEndpoints tests that health checks use Server.Ping
+func TestMultiServerCheckEndpoints(t *testing.T) {
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"message":"pong"}`))
+	}))
+	defer healthy.Close()
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	defer broken.Close()
+
+	server := NewMultiServer(broken.URL, healthy.URL)
+	states := server.CheckEndpoints(context.Background())
+
+	if len(states) != 2 {
+		t.Fatalf("Expected 2 endpoint states, got %d", len(states))
+	}
+	if states[0].Healthy {
+		t.Error("Expected endpoint with invalid ping response to be unhealthy")
+	}
+	if !states[1].Healthy || !states[1].Current {
+		t.Error("Expected healthy endpoint to become current")
+	}
+	if server.Endpoints().Current() != healthy.URL {
+		t.Errorf("Expected current endpoint %s, got %s", healthy.URL, server.Endpoints().Current())
+	}
+}
+
+// TestSingleServerEndpoints tests that a single server has no endpoint pool
+func TestSingleServerEndpoints(t *testing.T) {
+	server := NewServer("https://example.com")
+	if server.Endpoints() != nil {
+		t.Error("Expected no endpoint pool for a single server")
+	}
+	if server.CheckEndpoints(context.Background()) != nil {
+		t.Error("Expected no endpoint states for a single server")
+	}
+}
+
+// TestMultiServerNoReplay tests that a POST that may have reached the server is not repeated elsewhere
+func TestMultiServerNoReplay(t *testing.T) {
+	// Accepts the request, then drops the connection without answering
+	var dropped atomic.Int32
+	flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		dropped.Add(1)
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		conn.Close()
+	}))
+	defer flaky.Close()
+
+	var posts atomic.Int32
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			posts.Add(1)
+		}
+		w.Write([]byte(`{"result":{"code":800,"msg":""},"groups":[1]}`))
+	}))
+	defer up.Close()
+
+	server := NewMultiServer(flaky.URL, up.URL)
+	group := &Group{client: server.client, GroupID: 1}
+	if _, err := group.SendText(context.Background(), "hello"); err == nil {
+		t.Fatal("Expected the dropped send to fail")
+	}
+	if dropped.Load() == 0 || posts.Load() != 0 {
+		t.Errorf("Expected the message not to be replayed, got %d posts", posts.Load())
+	}
+	if states := server.Endpoints().States(); states[0].Healthy {
+		t.Error("Expected the dropping endpoint to be unhealthy")
+	}
+
+	// Idempotent requests still fail over
+	user := &User{client: server.client}
+	if _, err := user.GetGroups(context.Background()); err != nil {
+		t.Errorf("Expected GET to fail over, got %v", err)
+	}
+}
+
+// TestMultiServerLocalError tests that errors that never reached the network keep endpoints healthy
+func TestMultiServerLocalError(t *testing.T) {
+	var requests atomic.Int32
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Write([]byte(`{"result":{"code":800,"msg":""}}`))
+	}))
+	defer up.Close()
+	other := httptest.NewServer(http.NotFoundHandler())
+	defer other.Close()
+
+	server := NewMultiServer(up.URL, other.URL)
+	if _, err := server.client.doRequest(context.Background(), "POST", "/api/v1/group", map[string]any{"bad": make(chan int)}); err == nil {
+		t.Fatal("Expected the marshal error to be returned")
+	}
+	if requests.Load() != 0 {
+		t.Errorf("Expected no request to be sent, got %d", requests.Load())
+	}
+	for _, state := range server.Endpoints().States() {
+		if !state.Healthy {
+			t.Errorf("Expected %s to stay healthy", state.URL)
+		}
+	}
+}
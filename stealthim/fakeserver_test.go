@@ -0,0 +1,625 @@
+package stealthim
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// fakeUser is a registered user of the fake server
+type fakeUser struct {
+	info     UserInfo
+	password string
+}
+
+// fakeGroup is a group stored by the fake server
+type fakeGroup struct {
+	id         int64
+	name       string
+	password   string
+	createTime string
+	members    []GroupMember
+	messages   []Message
+}
+
+// member returns the index of username in the member list, or -1
+func (g *fakeGroup) member(username string) int {
+	for i, m := range g.members {
+		if m.Name == username {
+			return i
+		}
+	}
+	return -1
+}
+
+// Failure codes of the fake server. The SDK does not interpret them, tests only compare
+// them with StealthError.Code.
+const (
+	fakeCodeUnauthorized       = 901
+	fakeCodeUserNotFound       = 1201
+	fakeCodeUserAlreadyExists  = 1202
+	fakeCodeUserPasswordError  = 1203
+	fakeCodeGroupNotFound      = 1301
+	fakeCodePermissionDenied   = 1302
+	fakeCodeGroupPasswordError = 1303
+	fakeCodeAlreadyGroupMember = 1304
+	fakeCodeNotGroupMember     = 1305
+	fakeCodeFileNotFound       = 1401
+)
+
+// resultCode returns the result code of a server rejection, or 0 for other errors
+func resultCode(err error) int {
+	var stealthErr *StealthError
+	if errors.As(err, &stealthErr) {
+		return stealthErr.Code
+	}
+	return 0
+}
+
+// fakeServer is an in-memory implementation of the StealthIM REST API for tests
+type fakeServer struct {
+	*httptest.Server
+
+	mu              sync.Mutex
+	users           map[string]*fakeUser
+	sessions        map[string]string
+	groups          map[int64]*fakeGroup
+	nextGroupID     int64
+	nextMsgID       int64
+	nextSession     int64
+	noSessions      bool  // Answer session management requests with 404, like older servers
+	noPublicMembers bool  // Leave the member list out of public group profiles
+	noReceipts      bool  // Leave the message ID and time out of send replies, like older servers
+	noClientIDs     bool  // Ignore the client IDs of sent messages, like older servers
+	blockSize       int64 // Upload block size advertised by ping, 0 for the default
+	files           map[string][][]byte
+	ranges          []string // Range headers of file downloads
+}
+
+// newFakeServer starts a fake StealthIM server that is closed when the test ends
+func newFakeServer(t *testing.T) *fakeServer {
+	t.Helper()
+	fs := &fakeServer{
+		users:       make(map[string]*fakeUser),
+		sessions:    make(map[string]string),
+		groups:      make(map[int64]*fakeGroup),
+		files:       make(map[string][][]byte),
+		nextGroupID: 1,
+		nextMsgID:   1,
+	}
+	fs.Server = httptest.NewServer(http.HandlerFunc(fs.handle))
+	t.Cleanup(fs.Close)
+	return fs
+}
+
+// login registers a user on the fake server and logs it in
+func (fs *fakeServer) login(t *testing.T, username string) *User {
+	t.Helper()
+	server := NewServer(fs.URL)
+	if _, err := server.Register(context.Background(), username, "Ab123456", username, username+"@example.com", "1234567890"); err != nil {
+		t.Fatalf("Failed to register %s: %v", username, err)
+	}
+	user, _, err := server.Login(context.Background(), username, "Ab123456")
+	if err != nil {
+		t.Fatalf("Failed to login %s: %v", username, err)
+	}
+	return user
+}
+
+// createGroup creates a group owned by owner with the given additional members
+func (fs *fakeServer) createGroup(t *testing.T, owner *User, name string, members ...string) *Group {
+	t.Helper()
+	group, err := (&Group{}).Create(context.Background(), owner, name)
+	if err != nil {
+		t.Fatalf("Failed to create group: %v", err)
+	}
+	for _, member := range members {
+		if err := group.Invite(context.Background(), member); err != nil {
+			t.Fatalf("Failed to invite %s: %v", member, err)
+		}
+	}
+	return group
+}
+
+// reply writes a JSON response with the given result code and extra fields
+func (fs *fakeServer) reply(w http.ResponseWriter, code int, fields map[string]any) {
+	if fields == nil {
+		fields = map[string]any{}
+	}
+	result := Result{Code: code}
+	if code != CodeSuccess {
+		result.Msg = fmt.Sprintf("error %d", code)
+	}
+	fields["result"] = result
+	json.NewEncoder(w).Encode(fields)
+}
+
+// handle routes fake API requests
+func (fs *fakeServer) handle(w http.ResponseWriter, r *http.Request) {
+	var body map[string]any
+	if r.Body != nil {
+		json.NewDecoder(r.Body).Decode(&body)
+	}
+	str := func(key string) string {
+		v, _ := body[key].(string)
+		return v
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1/")
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+
+	if path == "ping" {
+		json.NewEncoder(w).Encode(map[string]any{
+			"message":  "pong",
+			"features": map[string]any{"block_size": fs.blockSize, "range": true},
+		})
+		return
+	}
+	if parts[0] == "file" {
+		fs.handleFile(w, r, parts[1:])
+		return
+	}
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	// Registration and login do not need a session
+	if parts[0] == "user" && len(parts) == 2 && parts[1] == "register" && r.Method == http.MethodPost {
+		username := str("username")
+		if _, ok := fs.users[username]; ok {
+			fs.reply(w, fakeCodeUserAlreadyExists, nil)
+			return
+		}
+		fs.users[username] = &fakeUser{
+			info: UserInfo{
+				Username:    username,
+				Nickname:    str("nickname"),
+				Email:       str("email"),
+				PhoneNumber: str("phone_number"),
+				CreateTime:  Timestamp{Time: time.Unix(1700000000, 0)},
+			},
+			password: str("password"),
+		}
+		fs.reply(w, CodeSuccess, map[string]any{"user_info": fs.users[username].info})
+		return
+	}
+	if parts[0] == "user" && len(parts) == 1 && r.Method == http.MethodPost {
+		user, ok := fs.users[str("username")]
+		if !ok {
+			fs.reply(w, fakeCodeUserNotFound, nil)
+			return
+		}
+		if user.password != str("password") {
+			fs.reply(w, fakeCodeUserPasswordError, nil)
+			return
+		}
+		fs.nextSession++
+		session := fmt.Sprintf("session-%d", fs.nextSession)
+		fs.sessions[session] = user.info.Username
+		fs.reply(w, CodeSuccess, map[string]any{"session": session, "user_info": user.info})
+		return
+	}
+
+	self, ok := fs.sessions[strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")]
+	if !ok {
+		fs.reply(w, fakeCodeUnauthorized, nil)
+		return
+	}
+
+	switch parts[0] {
+	case "user":
+		if len(parts) > 1 && parts[1] == "session" {
+			fs.handleSession(w, r, self, parts[2:])
+			return
+		}
+		fs.handleUser(w, r, self, parts[1:], str, body)
+	case "group":
+		fs.handleGroup(w, r, self, parts[1:], str, body)
+	case "message":
+		fs.handleMessage(w, r, self, parts[1:], str, body)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// handleUser serves /api/v1/user endpoints that require a session
+func (fs *fakeServer) handleUser(w http.ResponseWriter, r *http.Request, self string, parts []string, str func(string) string, body map[string]any) {
+	user := fs.users[self]
+	switch {
+	case len(parts) == 0 && r.Method == http.MethodGet:
+		fs.reply(w, CodeSuccess, map[string]any{"user_info": user.info})
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		other, ok := fs.users[parts[0]]
+		if !ok {
+			fs.reply(w, fakeCodeUserNotFound, nil)
+			return
+		}
+		fs.reply(w, CodeSuccess, map[string]any{"user_info": other.info})
+	case len(parts) == 1 && parts[0] == "password" && r.Method == http.MethodPut:
+		user.password = str("password")
+		// Changing the password signs out every session of the account
+		for token, username := range fs.sessions {
+			if username == self {
+				delete(fs.sessions, token)
+			}
+		}
+		fs.reply(w, CodeSuccess, nil)
+	case len(parts) == 1 && r.Method == http.MethodPut && (parts[0] == "email" || parts[0] == "nickname" || parts[0] == "phone"):
+		fields := map[string]*string{
+			"email":    &user.info.Email,
+			"nickname": &user.info.Nickname,
+			"phone":    &user.info.PhoneNumber,
+		}
+		key := parts[0]
+		if key == "phone" {
+			key = "phone_number"
+		}
+		*fields[parts[0]] = str(key)
+		fs.reply(w, CodeSuccess, nil)
+	case len(parts) == 0 && r.Method == http.MethodPut:
+		fields := map[string]*string{
+			"nickname":     &user.info.Nickname,
+			"email":        &user.info.Email,
+			"phone_number": &user.info.PhoneNumber,
+			"password":     &user.password,
+		}
+		for key, field := range fields {
+			if _, ok := body[key]; ok {
+				*field = str(key)
+			}
+		}
+		fs.reply(w, CodeSuccess, nil)
+	case len(parts) == 0 && r.Method == http.MethodDelete:
+		delete(fs.users, self)
+		for token, username := range fs.sessions {
+			if username == self {
+				delete(fs.sessions, token)
+			}
+		}
+		fs.reply(w, CodeSuccess, nil)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// handleSession serves the session management endpoints
+func (fs *fakeServer) handleSession(w http.ResponseWriter, r *http.Request, self string, parts []string) {
+	if fs.noSessions {
+		http.NotFound(w, r)
+		return
+	}
+	current := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	switch {
+	case len(parts) == 0 && r.Method == http.MethodGet:
+		sessions := []SessionInfo{}
+		for token, username := range fs.sessions {
+			if username == self {
+				sessions = append(sessions, SessionInfo{
+					ID:         strings.TrimPrefix(token, "session-"),
+					Current:    token == current,
+					CreateTime: Timestamp{Time: time.Unix(1700000000, 0)},
+					LastActive: Timestamp{Time: time.Now()},
+				})
+			}
+		}
+		fs.reply(w, CodeSuccess, map[string]any{"sessions": sessions})
+	case len(parts) == 0 && r.Method == http.MethodDelete:
+		delete(fs.sessions, current)
+		fs.reply(w, CodeSuccess, nil)
+	case len(parts) == 1 && r.Method == http.MethodDelete:
+		token := "session-" + parts[0]
+		if fs.sessions[token] != self {
+			fs.reply(w, fakeCodePermissionDenied, nil)
+			return
+		}
+		delete(fs.sessions, token)
+		fs.reply(w, CodeSuccess, nil)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// handleGroup serves /api/v1/group endpoints
+func (fs *fakeServer) handleGroup(w http.ResponseWriter, r *http.Request, self string, parts []string, str func(string) string, body map[string]any) {
+	if len(parts) == 0 {
+		switch r.Method {
+		case http.MethodGet:
+			groups := []int64{}
+			for id, g := range fs.groups {
+				if g.member(self) >= 0 {
+					groups = append(groups, id)
+				}
+			}
+			fs.reply(w, CodeSuccess, map[string]any{"groups": groups})
+		case http.MethodPost:
+			id := fs.nextGroupID
+			fs.nextGroupID++
+			fs.groups[id] = &fakeGroup{
+				id:         id,
+				name:       str("name"),
+				createTime: "1700000000",
+				members:    []GroupMember{{Name: self, Type: int(Owner)}},
+			}
+			fs.reply(w, CodeSuccess, map[string]any{"groupid": id})
+		default:
+			http.NotFound(w, r)
+		}
+		return
+	}
+
+	id, err := strconv.ParseInt(parts[0], 10, 64)
+	group, ok := fs.groups[id]
+	if err != nil || !ok {
+		fs.reply(w, fakeCodeGroupNotFound, nil)
+		return
+	}
+	selfIndex := group.member(self)
+	role := GroupMemberType(-1)
+	if selfIndex >= 0 {
+		role = GroupMemberType(group.members[selfIndex].Type)
+	}
+
+	action := ""
+	if len(parts) > 1 {
+		action = parts[1]
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		if selfIndex < 0 {
+			fs.reply(w, fakeCodeNotGroupMember, nil)
+			return
+		}
+		fs.reply(w, CodeSuccess, map[string]any{"members": group.members})
+	case action == "" && r.Method == http.MethodPost:
+		if selfIndex >= 0 {
+			fs.reply(w, fakeCodeAlreadyGroupMember, nil)
+			return
+		}
+		if group.password != str("password") {
+			fs.reply(w, fakeCodeGroupPasswordError, nil)
+			return
+		}
+		group.members = append(group.members, GroupMember{Name: self, Type: int(Member)})
+		fs.reply(w, CodeSuccess, nil)
+	case action == "" && r.Method == http.MethodDelete:
+		if role != Owner {
+			fs.reply(w, fakeCodePermissionDenied, nil)
+			return
+		}
+		delete(fs.groups, id)
+		fs.reply(w, CodeSuccess, nil)
+	case action == "leave" && r.Method == http.MethodPost:
+		if selfIndex < 0 {
+			fs.reply(w, fakeCodeNotGroupMember, nil)
+			return
+		}
+		if role == Owner && len(group.members) > 1 {
+			fs.reply(w, fakeCodePermissionDenied, nil)
+			return
+		}
+		group.members = append(group.members[:selfIndex], group.members[selfIndex+1:]...)
+		fs.reply(w, CodeSuccess, nil)
+	case action == "public" && r.Method == http.MethodGet:
+		policy := JoinPolicyPassword
+		if group.password == "" {
+			policy = JoinPolicyOpen
+		}
+		members := group.members
+		if fs.noPublicMembers {
+			members = nil
+		}
+		fs.reply(w, CodeSuccess, map[string]any{
+			"name":         group.name,
+			"create_time":  group.createTime,
+			"member_count": len(group.members),
+			"join_policy":  policy,
+			"members":      members,
+		})
+	case action == "invite" && r.Method == http.MethodPost:
+		username := str("username")
+		if selfIndex < 0 {
+			fs.reply(w, fakeCodePermissionDenied, nil)
+			return
+		}
+		if _, ok := fs.users[username]; !ok {
+			fs.reply(w, fakeCodeUserNotFound, nil)
+			return
+		}
+		if group.member(username) >= 0 {
+			fs.reply(w, fakeCodeAlreadyGroupMember, nil)
+			return
+		}
+		group.members = append(group.members, GroupMember{Name: username, Type: int(Member)})
+		fs.reply(w, CodeSuccess, nil)
+	case action == "kick" && r.Method == http.MethodPost:
+		target := group.member(str("username"))
+		if target < 0 {
+			fs.reply(w, fakeCodeNotGroupMember, nil)
+			return
+		}
+		if role < Manager || GroupMemberType(group.members[target].Type) >= role {
+			fs.reply(w, fakeCodePermissionDenied, nil)
+			return
+		}
+		group.members = append(group.members[:target], group.members[target+1:]...)
+		fs.reply(w, CodeSuccess, nil)
+	case action == "role" && r.Method == http.MethodPut:
+		target := group.member(str("username"))
+		newRole, _ := body["type"].(float64)
+		if target < 0 {
+			fs.reply(w, fakeCodeNotGroupMember, nil)
+			return
+		}
+		if role != Owner {
+			fs.reply(w, fakeCodePermissionDenied, nil)
+			return
+		}
+		group.members[target].Type = int(newRole)
+		fs.reply(w, CodeSuccess, nil)
+	case action == "name" && r.Method == http.MethodPut:
+		if role < Manager {
+			fs.reply(w, fakeCodePermissionDenied, nil)
+			return
+		}
+		group.name = str("name")
+		fs.reply(w, CodeSuccess, nil)
+	case action == "password" && r.Method == http.MethodPut:
+		if role < Manager {
+			fs.reply(w, fakeCodePermissionDenied, nil)
+			return
+		}
+		group.password = str("password")
+		fs.reply(w, CodeSuccess, nil)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// handleMessage serves /api/v1/message endpoints
+func (fs *fakeServer) handleMessage(w http.ResponseWriter, r *http.Request, self string, parts []string, str func(string) string, body map[string]any) {
+	if len(parts) != 1 {
+		http.NotFound(w, r)
+		return
+	}
+	id, err := strconv.ParseInt(parts[0], 10, 64)
+	group, ok := fs.groups[id]
+	if err != nil || !ok {
+		fs.reply(w, fakeCodeGroupNotFound, nil)
+		return
+	}
+	if group.member(self) < 0 {
+		fs.reply(w, fakeCodeNotGroupMember, nil)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		// A repeated client ID returns the stored message instead of adding it again
+		clientID := str("client_id")
+		if fs.noClientIDs {
+			clientID = ""
+		}
+		if clientID != "" {
+			for _, msg := range group.messages {
+				if msg.ClientID == clientID {
+					fs.reply(w, CodeSuccess, map[string]any{
+						"msgid": msg.MsgID,
+						"time":  strconv.FormatInt(msg.Time.Unix(), 10),
+					})
+					return
+				}
+			}
+		}
+		msgType, _ := body["type"].(float64)
+		msg := Message{
+			GroupID:  parts[0],
+			Msg:      str("msg"),
+			MsgID:    strconv.FormatInt(fs.nextMsgID, 10),
+			Time:     Timestamp{Time: time.Now()},
+			Type:     int(msgType),
+			Username: self,
+			ClientID: clientID,
+		}
+		fs.nextMsgID++
+		group.messages = append(group.messages, msg)
+		if fs.noReceipts {
+			fs.reply(w, CodeSuccess, nil)
+			return
+		}
+		fs.reply(w, CodeSuccess, map[string]any{
+			"msgid": msg.MsgID,
+			"time":  strconv.FormatInt(msg.Time.Unix(), 10),
+		})
+	case http.MethodGet:
+		// Send the stored messages as a single SSE event and end the stream
+		data, _ := json.Marshal(map[string]any{
+			"result": Result{Code: CodeSuccess},
+			"msg":    group.messages,
+		})
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "data: %s\n\n", data)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// handleFile serves WebSocket uploads and Streamable HTTP downloads
+func (fs *fakeServer) handleFile(w http.ResponseWriter, r *http.Request, parts []string) {
+	if len(parts) == 1 && parts[0] != "" && r.Method == http.MethodGet {
+		fs.mu.Lock()
+		blocks, ok := fs.files[parts[0]]
+		rangeHeader := r.Header.Get("Range")
+		if rangeHeader != "" {
+			fs.ranges = append(fs.ranges, rangeHeader)
+		}
+		fs.mu.Unlock()
+		writeFrame := func(id uint32, data []byte) {
+			header := make([]byte, 8)
+			binary.LittleEndian.PutUint32(header[:4], id)
+			binary.LittleEndian.PutUint32(header[4:], uint32(len(data)))
+			w.Write(append(header, data...))
+		}
+		code := CodeSuccess
+		if !ok {
+			code = fakeCodeFileNotFound
+		}
+		// Send every block that overlaps the requested byte range
+		first, last := int64(0), int64(-1)
+		if _, err := fmt.Sscanf(rangeHeader, "bytes=%d-%d", &first, &last); err != nil {
+			first, last = 0, -1
+		}
+		offset := int64(0)
+		for i, block := range blocks {
+			end := offset + int64(len(block))
+			if last < 0 || (end > first && offset <= last) {
+				writeFrame(uint32(i), block)
+			}
+			offset = end
+		}
+		end, _ := json.Marshal(map[string]any{"result": Result{Code: code}})
+		writeFrame(0xffffffff, end)
+		return
+	}
+
+	conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	var metadata FileMetadata
+	if err := conn.ReadJSON(&metadata); err != nil {
+		return
+	}
+	size, _ := strconv.ParseInt(metadata.Size, 10, 64)
+	conn.WriteJSON(map[string]any{"result": Result{Code: CodeSuccess}, "type": "meta"})
+
+	var blocks [][]byte
+	for received := int64(0); received < size; {
+		_, data, err := conn.ReadMessage()
+		if err != nil || len(data) < 4 {
+			return
+		}
+		blockID := binary.LittleEndian.Uint32(data[:4])
+		blocks = append(blocks, data[4:])
+		received += int64(len(data) - 4)
+		conn.WriteJSON(map[string]any{"result": Result{Code: CodeSuccess}, "type": "block", "blockid": blockID})
+	}
+
+	fs.mu.Lock()
+	fs.files[metadata.Hash] = blocks
+	fs.mu.Unlock()
+	conn.WriteJSON(map[string]any{"result": Result{Code: CodeSuccess}, "type": "complete"})
+}
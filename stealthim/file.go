@@ -4,8 +4,10 @@ import (
 	"context"
 	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"os"
@@ -13,120 +15,199 @@ import (
 	"time"
 
 	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel/attribute"
 	"lukechampine.com/blake3"
 )
 
 // SendFile uploads a file to the group using WebSocket
 func (g *Group) SendFile(ctx context.Context, filename, filepath string) error {
-	// Connect to the WebSocket endpoint
-	// Convert HTTP/HTTPS URL to WebSocket URL
-	var wsURL string
-	if len(g.client.BaseURL) >= 8 && g.client.BaseURL[:8] == "https://" {
-		wsURL = "wss" + g.client.BaseURL[5:] + "/api/v1/file/"
-	} else if len(g.client.BaseURL) >= 7 && g.client.BaseURL[:7] == "http://" {
-		wsURL = "ws" + g.client.BaseURL[4:] + "/api/v1/file/"
-	} else {
-		// 如果不是标准格式，尝试直接替换
-		if len(g.client.BaseURL) >= 5 && g.client.BaseURL[:5] == "https" {
-			wsURL = "wss" + g.client.BaseURL[5:] + "/api/v1/file/"
-		} else {
-			wsURL = "ws" + g.client.BaseURL[4:] + "/api/v1/file/"
-		}
-	}
+	_, err := g.sendFile(ctx, filename, filepath)
+	return err
+}
 
-	// Add authorization as query parameter if available
-	if g.client.Session != "" {
-		// Parse URL and add authorization parameter
-		u, err := url.Parse(wsURL)
+// sendFile uploads a file and returns its hash
+func (g *Group) sendFile(ctx context.Context, filename, filepath string) (hash string, err error) {
+	tel := g.client.telemetry()
+	ctx, span := tel.startSpan(ctx, "StealthIM SendFile",
+		attribute.Int64("stealthim.group.id", g.GroupID),
+		attribute.String("stealthim.file.name", filename),
+	)
+	defer func() {
 		if err != nil {
-			return fmt.Errorf("failed to parse WebSocket URL: %w", err)
+			tel.recordError(ctx, span, err, 0)
 		}
-
-		// Add authorization parameter
-		q := u.Query()
-		q.Set("authorization", g.client.Session)
-		u.RawQuery = q.Encode()
-
-		wsURL = u.String()
-	}
-
-	// 准备 WebSocket 请求头
-	headers := make(http.Header)
-	headers.Set("User-Agent", "StealthIM-GoSDK/1.0")
-	headers.Set("Origin", g.client.BaseURL)
+		span.End()
+	}()
 
 	// Open the file
 	file, err := os.Open(filepath)
 	if err != nil {
-		return fmt.Errorf("failed to open file: %w", err)
+		return "", fmt.Errorf("failed to open file: %w", err)
 	}
 	defer file.Close()
 
 	// Get file info
 	fileInfo, err := file.Stat()
 	if err != nil {
-		return fmt.Errorf("failed to get file info: %w", err)
+		return "", fmt.Errorf("failed to get file info: %w", err)
 	}
 	fileSize := fileInfo.Size()
+	span.SetAttributes(attribute.Int64("stealthim.file.size", fileSize))
+
+	// Use the block size and size limit advertised by the server
+	blockSize, maxFileSize, err := g.client.uploadLimits(ctx)
+	if err != nil {
+		return "", err
+	}
+	if maxFileSize > 0 && fileSize > maxFileSize {
+		return "", fmt.Errorf("%w: %d bytes exceeds the server limit of %d bytes", ErrFileTooLarge, fileSize, maxFileSize)
+	}
 
 	// Calculate hash using Blake3 algorithm
-	// The algorithm should split the file into 2048 KiB blocks and hash each block
+	// The algorithm should split the file into blocks of the server block size and hash each block
 	// then concatenate the binary hash results and hash again
-	hash, err := calculateBlake3Hash(filepath)
+	hash, err = calculateBlake3Hash(filepath, blockSize)
 	if err != nil {
-		return fmt.Errorf("failed to calculate file hash: %w", err)
+		return "", fmt.Errorf("failed to calculate file hash: %w", err)
 	}
 
 	// Prepare metadata
-	metadata := FileMetadata{
+	metadata := &FileMetadata{
 		Size:     fmt.Sprintf("%d", fileSize),
 		GroupID:  fmt.Sprintf("%d", g.GroupID),
 		Hash:     hash,
 		Filename: filename,
 	}
 
+	// 准备 WebSocket 请求头
+	call := &Call{
+		Method:   "WS",
+		Endpoint: "/api/v1/file/",
+		Body:     metadata,
+		Header:   make(http.Header),
+	}
+	call.Header.Set("User-Agent", "StealthIM-GoSDK/1.0")
+
+	reply, err := g.client.invoke(ctx, call, func(ctx context.Context, call *Call) (*Reply, error) {
+		return g.upload(ctx, call, file, fileSize, blockSize)
+	})
+	if err != nil {
+		return "", err
+	}
+	if !reply.Result.IsSuccess() {
+		return "", fmt.Errorf("file upload failed: %w", reply.Result.ToError())
+	}
+	return hash, nil
+}
+
+// upload streams the file over a WebSocket connection as described by call.
+// Rejections by the server are reported through the reply result.
+func (g *Group) upload(ctx context.Context, call *Call, file *os.File, fileSize, blockSize int64) (*Reply, error) {
+	// Connect to the WebSocket endpoint
+	// Convert HTTP/HTTPS URL to WebSocket URL
+	baseURL := g.client.baseURL(ctx)
+	var wsURL string
+	if len(baseURL) >= 8 && baseURL[:8] == "https://" {
+		wsURL = "wss" + baseURL[5:] + call.Endpoint
+	} else if len(baseURL) >= 7 && baseURL[:7] == "http://" {
+		wsURL = "ws" + baseURL[4:] + call.Endpoint
+	} else {
+		// 如果不是标准格式，尝试直接替换
+		if len(baseURL) >= 5 && baseURL[:5] == "https" {
+			wsURL = "wss" + baseURL[5:] + call.Endpoint
+		} else {
+			wsURL = "ws" + baseURL[4:] + call.Endpoint
+		}
+	}
+
+	// Add authorization as query parameter if available
+	if session := g.client.session(); session != "" {
+		// Parse URL and add authorization parameter
+		u, err := url.Parse(wsURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse WebSocket URL: %w", err)
+		}
+
+		// Add authorization parameter
+		q := u.Query()
+		q.Set("authorization", session)
+		u.RawQuery = q.Encode()
+
+		wsURL = u.String()
+	}
+
+	headers := call.Header.Clone()
+	if headers.Get("Origin") == "" {
+		headers.Set("Origin", baseURL)
+	}
+	tel := g.client.telemetry()
+	tel.inject(ctx, headers)
+
+	var hash string
+	if metadata, ok := call.Body.(*FileMetadata); ok {
+		hash = metadata.Hash
+	}
+
+	// Rewind the file in case an interceptor retries the upload
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to rewind file: %w", err)
+	}
+
 	// 设置超时上下文
 	dialer := websocket.DefaultDialer
 	dialer.HandshakeTimeout = 10 * time.Second
 
+	logger := g.client.logger()
+
 	// Attempt to connect to the WebSocket
-	conn, _, err := dialer.Dial(wsURL, headers)
+	conn, handshake, err := dialer.Dial(wsURL, headers)
 	if err != nil {
-		return fmt.Errorf("failed to connect to WebSocket: %w", err)
+		logger.LogAttrs(ctx, slog.LevelWarn, "file upload connection failed",
+			slog.String("url", redactURL(wsURL)),
+			slog.Int64("group_id", g.GroupID),
+			slog.Any("error", err),
+		)
+		if errors.Is(err, websocket.ErrBadHandshake) {
+			return nil, fmt.Errorf("failed to connect to WebSocket: %w", err) // The server answered
+		}
+		return nil, &transportError{op: "failed to connect to WebSocket", err: err}
 	}
 	defer conn.Close()
 
 	// Send metadata
-	if err := conn.WriteJSON(metadata); err != nil {
-		return fmt.Errorf("failed to send metadata: %w", err)
+	if err := conn.WriteJSON(call.Body); err != nil {
+		return nil, fmt.Errorf("failed to send metadata: %w", err)
 	}
 
 	// Read response for metadata
 	var metaResponse struct {
-		Result struct {
-			Code int    `json:"code"`
-			Msg  string `json:"msg"`
-		} `json:"result"`
-		Type string `json:"type"`
+		Result Result `json:"result"`
+		Type   string `json:"type"`
 	}
 	if err := conn.ReadJSON(&metaResponse); err != nil {
-		return fmt.Errorf("failed to read metadata response: %w", err)
+		return nil, fmt.Errorf("failed to read metadata response: %w", err)
 	}
 
-	if metaResponse.Result.Code != 800 {
-		return fmt.Errorf("metadata upload failed: %s", metaResponse.Result.Msg)
+	if !metaResponse.Result.IsSuccess() {
+		logger.LogAttrs(ctx, slog.LevelWarn, "file metadata rejected",
+			slog.Int64("group_id", g.GroupID),
+			slog.String("hash", hash),
+			slog.Int("code", metaResponse.Result.Code),
+			slog.String("result_msg", metaResponse.Result.Msg),
+		)
+		return &Reply{Result: metaResponse.Result, Response: handshake}, nil
 	}
 
 	// Upload file in chunks
-	blockSize := int64(2048 * 1024) // 2048 KiB
 	buffer := make([]byte, blockSize)
 	blockID := int32(0)
+	sent := int64(0)
 
 	for {
-		// Read a block from the file
-		n, err := file.Read(buffer)
-		if err != nil && err != io.EOF {
-			return fmt.Errorf("failed to read file: %w", err)
+		// Read a full block from the file, only the last one may be shorter
+		n, err := io.ReadFull(file, buffer)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return nil, fmt.Errorf("failed to read file: %w", err)
 		}
 		if n == 0 {
 			break // End of file
@@ -139,36 +220,45 @@ func (g *Group) SendFile(ctx context.Context, filename, filepath string) error {
 
 		// Send the block via WebSocket binary message
 		if err := conn.WriteMessage(websocket.BinaryMessage, blockData); err != nil {
-			return fmt.Errorf("failed to send block: %w", err)
+			return nil, fmt.Errorf("failed to send block: %w", err)
 		}
 
 		// Read response for this block
 		var blockResponse struct {
-			Result struct {
-				Code int    `json:"code"`
-				Msg  string `json:"msg"`
-			} `json:"result"`
+			Result  Result `json:"result"`
 			Type    string `json:"type"`
 			BlockID int32  `json:"blockid"`
 		}
 		if err := conn.ReadJSON(&blockResponse); err != nil {
-			return fmt.Errorf("failed to read block response: %w", err)
+			return nil, fmt.Errorf("failed to read block response: %w", err)
 		}
 
-		if blockResponse.Result.Code != 800 {
-			return fmt.Errorf("block upload failed: %s", blockResponse.Result.Msg)
+		if !blockResponse.Result.IsSuccess() {
+			logger.LogAttrs(ctx, slog.LevelWarn, "file block rejected",
+				slog.String("hash", hash),
+				slog.Int("block_id", int(blockID)),
+				slog.Int("code", blockResponse.Result.Code),
+				slog.String("result_msg", blockResponse.Result.Msg),
+			)
+			return &Reply{Result: blockResponse.Result, Response: handshake}, nil
 		}
 
+		sent += int64(n)
+		tel.recordBytes(ctx, directionUpload, int64(n))
+		logger.LogAttrs(ctx, slog.LevelDebug, "file block acknowledged",
+			slog.String("hash", hash),
+			slog.Int("block_id", int(blockResponse.BlockID)),
+			slog.Int64("bytes_sent", sent),
+			slog.Int64("bytes_total", fileSize),
+		)
+
 		blockID++
 	}
 
 	// Wait for completion response
 	var completeResponse struct {
-		Result struct {
-			Code int    `json:"code"`
-			Msg  string `json:"msg"`
-		} `json:"result"`
-		Type string `json:"type"`
+		Result Result `json:"result"`
+		Type   string `json:"type"`
 	}
 	if err := conn.ReadJSON(&completeResponse); err != nil {
 		// Check if the error is due to connection being closed (EOF) after successful upload
@@ -176,20 +266,33 @@ func (g *Group) SendFile(ctx context.Context, filename, filepath string) error {
 			err.Error() == "EOF" ||
 			err.Error() == "read tcp: use of closed network connection" {
 			// This can be expected after successful file upload, so we return success
-			return nil
+			logger.LogAttrs(ctx, slog.LevelInfo, "file upload completed",
+				slog.String("hash", hash),
+				slog.Int64("bytes_total", fileSize),
+			)
+			return &Reply{Result: Result{Code: 800}, Response: handshake}, nil
 		}
-		return fmt.Errorf("failed to read completion response: %w", err)
+		return nil, fmt.Errorf("failed to read completion response: %w", err)
 	}
 
-	if completeResponse.Result.Code != 800 {
-		return fmt.Errorf("file upload failed: %s", completeResponse.Result.Msg)
+	if !completeResponse.Result.IsSuccess() {
+		logger.LogAttrs(ctx, slog.LevelWarn, "file upload failed",
+			slog.String("hash", hash),
+			slog.Int("code", completeResponse.Result.Code),
+			slog.String("result_msg", completeResponse.Result.Msg),
+		)
+		return &Reply{Result: completeResponse.Result, Response: handshake}, nil
 	}
 
-	return nil
+	logger.LogAttrs(ctx, slog.LevelInfo, "file upload completed",
+		slog.String("hash", hash),
+		slog.Int64("bytes_total", fileSize),
+	)
+	return &Reply{Result: completeResponse.Result, Response: handshake}, nil
 }
 
 // calculateBlake3Hash calculates the Blake3 hash according to the specified algorithm
-func calculateBlake3Hash(filepath string) (string, error) {
+func calculateBlake3Hash(filepath string, blockSize int64) (string, error) {
 	file, err := os.Open(filepath)
 	if err != nil {
 		return "", fmt.Errorf("failed to open file: %w", err)
@@ -202,14 +305,13 @@ func calculateBlake3Hash(filepath string) (string, error) {
 		return "", fmt.Errorf("failed to get file info: %w", err)
 	}
 
-	blockSize := int64(2048 * 1024) // 2048 KiB
 	var hashes []byte
 
 	// Process the file in blocks
 	buffer := make([]byte, blockSize)
 	for {
-		n, err := file.Read(buffer)
-		if err != nil && err != io.EOF {
+		n, err := io.ReadFull(file, buffer)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
 			return "", fmt.Errorf("failed to read file: %w", err)
 		}
 		if n == 0 {
@@ -236,104 +338,165 @@ type FileRangeBlock struct {
 // downloadFileRange downloads a specific range of a file using Streamable HTTP format
 // Returns a slice of blocks and the offset information needed for reassembly
 func (c *Client) downloadFileRange(ctx context.Context, fileHash, rangeHeader string) ([]FileRangeBlock, error) {
-	endpoint := fmt.Sprintf("/api/v1/file/%s", fileHash)
+	call := &Call{
+		Method:   "GET",
+		Endpoint: fmt.Sprintf("/api/v1/file/%s", fileHash),
+		Header:   make(http.Header),
+	}
 
-	// Create a direct HTTP request to handle the Streamable HTTP format
-	url := c.BaseURL + endpoint
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	// Set Range header if provided
+	if rangeHeader != "" {
+		call.Header.Set("Range", rangeHeader)
+	}
+
+	reply, err := c.invoke(ctx, call, c.fetchFile)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		c.logger().LogAttrs(ctx, slog.LevelWarn, "file download failed",
+			slog.String("hash", fileHash),
+			slog.String("range", rangeHeader),
+			slog.Any("error", err),
+		)
+		return nil, err
 	}
 
-	// Set authorization header if session is available
-	if c.Session != "" {
-		req.Header.Set("Authorization", "Bearer "+c.Session)
+	blocks, result, err := parseFileBlocks(reply.Body)
+	if err != nil {
+		return nil, err
+	}
+	if !result.IsSuccess() {
+		return nil, result.ToError()
 	}
 
-	// Set Range header if provided
-	if rangeHeader != "" {
-		req.Header.Set("Range", rangeHeader)
+	for _, block := range blocks {
+		c.telemetry().recordBytes(ctx, directionDownload, int64(len(block.Data)))
+		c.logger().LogAttrs(ctx, slog.LevelDebug, "file block received",
+			slog.String("hash", fileHash),
+			slog.Int("block_id", int(block.BlockID)),
+			slog.Int("bytes", len(block.Data)),
+		)
+	}
+
+	return blocks, nil
+}
+
+// fetchFile performs a file download request and buffers the Streamable HTTP body
+func (c *Client) fetchFile(ctx context.Context, call *Call) (*Reply, error) {
+	// Create a direct HTTP request to handle the Streamable HTTP format
+	req, err := c.newRequest(ctx, call, nil)
+	if err != nil {
+		return nil, err
 	}
 
 	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("download request failed: %w", err)
+		return nil, &transportError{op: "download request failed", err: err}
 	}
 	defer resp.Body.Close()
 
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, &transportError{op: "failed to read download response", err: err}
+	}
+
+	_, result, err := parseFileBlocks(body)
+	if err != nil {
+		return nil, err
+	}
+	return &Reply{Result: result, Response: resp, Body: body}, nil
+}
+
+// parseFileBlocks splits a Streamable HTTP body into blocks and returns the result of the end message
+func parseFileBlocks(body []byte) ([]FileRangeBlock, Result, error) {
 	// Handle Streamable HTTP format
 	// Format: 4-byte BlockID (little endian) + 4-byte message length (little endian) + message content
 	// BlockID 0xffffffff indicates end message
 
 	// Collect blocks in the order they arrive, but track them by their BlockID
 	var blocks []FileRangeBlock
-	var hasEndMessage bool = false
-
-	buf := make([]byte, 8) // For reading BlockID and length
-	for !hasEndMessage {
+	for {
 		// Read BlockID and length
-		_, err := io.ReadFull(resp.Body, buf)
-		if err != nil {
-			if err == io.EOF {
-				// This could happen after receiving the end message if the connection closes normally
-				// If we haven't received the end message yet, this is an error
-				if !hasEndMessage {
-					return nil, fmt.Errorf("unexpected EOF while reading block header, end message not received")
-				}
-				// If we have received the end message, EOF is expected
-				break
-			}
-			return nil, fmt.Errorf("failed to read block header: %w", err)
+		if len(body) < 8 {
+			// If we haven't received the end message yet, this is an error
+			return nil, Result{}, fmt.Errorf("unexpected EOF while reading block header, end message not received")
 		}
+		blockID := binary.LittleEndian.Uint32(body[0:4])
+		length := binary.LittleEndian.Uint32(body[4:8])
+		body = body[8:]
 
-		blockID := binary.LittleEndian.Uint32(buf[0:4])
-		length := binary.LittleEndian.Uint32(buf[4:8])
+		if uint64(len(body)) < uint64(length) {
+			return nil, Result{}, fmt.Errorf("failed to read block content: %w", io.ErrUnexpectedEOF)
+		}
+		content := body[:length]
+		body = body[length:]
 
 		// Check for end message
 		if blockID == 0xffffffff {
-			hasEndMessage = true
-
-			// Read the end message content
-			endMsgBuf := make([]byte, length)
-			_, err := io.ReadFull(resp.Body, endMsgBuf)
-			if err != nil {
-				return nil, fmt.Errorf("failed to read end message: %w", err)
-			}
-
 			// Parse the end message to check result
 			var endResponse struct {
 				Result Result `json:"result"`
 			}
-			if err := json.Unmarshal(endMsgBuf, &endResponse); err != nil {
+			if err := json.Unmarshal(content, &endResponse); err != nil {
 				// If we can't parse the end message, it might be a protocol error
-				return nil, fmt.Errorf("failed to parse end message: %w", err)
+				return nil, Result{}, fmt.Errorf("failed to parse end message: %w", err)
 			}
-
-			if !endResponse.Result.IsSuccess() {
-				return nil, endResponse.Result.ToError()
-			}
-		}
-
-		// Read the block content
-		blockBuf := make([]byte, length)
-		_, err = io.ReadFull(resp.Body, blockBuf)
-		if err != nil {
-			return nil, fmt.Errorf("failed to read block content: %w", err)
+			return blocks, endResponse.Result, nil
 		}
 
 		// Store the block with its ID
 		blocks = append(blocks, FileRangeBlock{
 			BlockID: blockID,
-			Data:    blockBuf,
+			Data:    content,
 		})
 	}
+}
 
-	return blocks, nil
+// DownloadOption configures Client.DownloadFile
+type DownloadOption func(*downloadOptions)
+
+// downloadOptions holds the settings of a single download
+type downloadOptions struct {
+	decrypt *EncryptedFile
+}
+
+// WithDecryption decrypts an end-to-end encrypted attachment while downloading it, see
+// E2EEGroup.SendFile. The file hash must be the one of the attachment.
+func WithDecryption(file *EncryptedFile) DownloadOption {
+	return func(o *downloadOptions) {
+		o.decrypt = file
+	}
 }
 
 // DownloadFile downloads a file with multi-threading support
 // This implementation handles the Streamable HTTP format as specified in the API
-func (c *Client) DownloadFile(ctx context.Context, fileHash, outputPath string, threads int) error {
+func (c *Client) DownloadFile(ctx context.Context, fileHash, outputPath string, threads int, opts ...DownloadOption) (err error) {
+	var options downloadOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	tel := c.telemetry()
+	ctx, span := tel.startSpan(ctx, "StealthIM DownloadFile",
+		attribute.String("stealthim.file.hash", fileHash),
+		attribute.Bool("stealthim.file.encrypted", options.decrypt != nil),
+	)
+	defer func() {
+		if err != nil {
+			tel.recordError(ctx, span, err, 0)
+		}
+		span.End()
+	}()
+
+	if options.decrypt != nil {
+		if options.decrypt.Hash != fileHash {
+			return fmt.Errorf("%w: hash %s does not belong to the attachment", ErrNotEncryptedFile, fileHash)
+		}
+		return c.downloadEncryptedFile(ctx, options.decrypt, outputPath, threads)
+	}
+	return c.downloadFile(ctx, fileHash, outputPath)
+}
+
+// downloadFile downloads a file and writes its blocks in order to outputPath
+func (c *Client) downloadFile(ctx context.Context, fileHash, outputPath string) error {
 	// For single-threaded download, use the simple approach
 	blocks, err := c.downloadFileRange(ctx, fileHash, "")
 	if err != nil {
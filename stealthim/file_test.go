@@ -86,7 +86,7 @@ func TestCalculateBlake3Hash(t *testing.T) {
 	tmpFile.Close()
 
 	// Test the hash calculation function
-	hash, err := calculateBlake3Hash(tmpFile.Name())
+	hash, err := calculateBlake3Hash(tmpFile.Name(), DefaultBlockSize)
 	if err != nil {
 		t.Errorf("calculateBlake3Hash failed: %v", err)
 	}
@@ -147,7 +147,7 @@ func TestClientDownloadFile(t *testing.T) {
 		t.Fatalf("Failed to write to temporary file: %v", err)
 	}
 	tmpFile.Close()
-	hash, err := calculateBlake3Hash(tmpFile.Name())
+	hash, err := calculateBlake3Hash(tmpFile.Name(), DefaultBlockSize)
 
 	// Test sending the file
 	// Note: This test requires a working server connection
@@ -254,7 +254,7 @@ func TestClientGetFileInfo(t *testing.T) {
 	defer cancel()
 
 	// Test the hash calculation function
-	hash, err := calculateBlake3Hash(tmpFile.Name())
+	hash, err := calculateBlake3Hash(tmpFile.Name(), DefaultBlockSize)
 	if err != nil {
 		t.Errorf("calculateBlake3Hash failed: %v", err)
 		t.Fail()
@@ -2,13 +2,29 @@ package stealthim
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
+)
+
+// Group membership errors
+var (
+	ErrOwnerCannotLeave  = errors.New("group owner cannot leave, transfer ownership or disband the group")
+	ErrNotGroupMember    = errors.New("not a group member")
+	ErrOwnershipTransfer = errors.New("ownership transfer failed")
 )
 
 // Group represents a group in StealthIM
 type Group struct {
 	client  *Client
 	GroupID int64
+
+	// CheckPermissions makes admin operations fail with a *PermissionError before any request
+	// is sent if the cached member list shows they are not allowed, see Permissions
+	CheckPermissions bool
+
+	mu   sync.Mutex
+	info *GroupInfo // Cached metadata, see Info
 }
 
 // Create creates a new group
@@ -92,32 +108,22 @@ func (g *Group) GetMembers(ctx context.Context) ([]GroupMember, error) {
 	return response.Members, nil
 }
 
-// GetInfo retrieves public group information
-func (g *Group) GetInfo(ctx context.Context) ([]GroupMember, error) {
-	endpoint := fmt.Sprintf("/api/v1/group/%d/public", g.GroupID)
-	resp, err := g.client.doRequest(ctx, "GET", endpoint, nil)
+// GetInfo retrieves the public group profile, which is also available to non-members
+func (g *Group) GetInfo(ctx context.Context) (*GroupPublicInfo, error) {
+	public, err := g.fetchPublic(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("get group info request failed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	var response struct {
-		Result  Result        `json:"result"`
-		Members []GroupMember `json:"members"`
-	}
-	if err := g.client.parseResponse(resp, &response); err != nil {
-		return nil, fmt.Errorf("failed to parse get group info response: %w", err)
+		return nil, err
 	}
 
-	if !response.Result.IsSuccess() {
-		return nil, response.Result.ToError()
-	}
-
-	return response.Members, nil
+	return public.publicInfo(g.GroupID), nil
 }
 
 // Invite invites a user to the group
 func (g *Group) Invite(ctx context.Context, username string) error {
+	if err := g.checkPermission(ctx, ActionInvite, username); err != nil {
+		return err
+	}
+
 	reqBody := map[string]any{
 		"username": username,
 	}
@@ -140,11 +146,19 @@ func (g *Group) Invite(ctx context.Context, username string) error {
 		return response.Result.ToError()
 	}
 
+	g.updateMembers(func(members []GroupMember) []GroupMember {
+		return append(members, GroupMember{Name: username, Type: int(Member)})
+	})
+
 	return nil
 }
 
 // SetMemberRole sets a user's role in the group
 func (g *Group) SetMemberRole(ctx context.Context, username string, role GroupMemberType) error {
+	if err := g.checkPermission(ctx, ActionSetRole, username); err != nil {
+		return err
+	}
+
 	reqBody := map[string]any{
 		"username": username,
 		"type":     int(role),
@@ -168,11 +182,24 @@ func (g *Group) SetMemberRole(ctx context.Context, username string, role GroupMe
 		return response.Result.ToError()
 	}
 
+	g.updateMembers(func(members []GroupMember) []GroupMember {
+		for i := range members {
+			if members[i].Name == username {
+				members[i].Type = int(role)
+			}
+		}
+		return members
+	})
+
 	return nil
 }
 
 // Kick removes a user from the group
 func (g *Group) Kick(ctx context.Context, username string) error {
+	if err := g.checkPermission(ctx, ActionKick, username); err != nil {
+		return err
+	}
+
 	reqBody := map[string]any{
 		"username": username,
 	}
@@ -195,11 +222,24 @@ func (g *Group) Kick(ctx context.Context, username string) error {
 		return response.Result.ToError()
 	}
 
+	g.updateMembers(func(members []GroupMember) []GroupMember {
+		for i := range members {
+			if members[i].Name == username {
+				return append(members[:i], members[i+1:]...)
+			}
+		}
+		return members
+	})
+
 	return nil
 }
 
 // ChangeName changes the group name
 func (g *Group) ChangeName(ctx context.Context, newName string) error {
+	if err := g.checkPermission(ctx, ActionChangeName, ""); err != nil {
+		return err
+	}
+
 	reqBody := map[string]any{
 		"name": newName,
 	}
@@ -222,11 +262,19 @@ func (g *Group) ChangeName(ctx context.Context, newName string) error {
 		return response.Result.ToError()
 	}
 
+	g.updateInfo(func(info *GroupInfo) {
+		info.Name = newName
+	})
+
 	return nil
 }
 
 // ChangePassword changes the group password
 func (g *Group) ChangePassword(ctx context.Context, newPassword string) error {
+	if err := g.checkPermission(ctx, ActionChangePassword, ""); err != nil {
+		return err
+	}
+
 	reqBody := map[string]any{
 		"password": newPassword,
 	}
@@ -251,3 +299,144 @@ func (g *Group) ChangePassword(ctx context.Context, newPassword string) error {
 
 	return nil
 }
+
+// Leave removes the current user from the group.
+// The owner cannot leave while other members remain; transfer ownership or disband the group first.
+func (g *Group) Leave(ctx context.Context) error {
+	self, err := g.client.selfUsername(ctx)
+	if err != nil {
+		return err
+	}
+	members, err := g.GetMembers(ctx)
+	if err != nil {
+		return err
+	}
+	if role, ok := memberRole(members, self); ok && role == Owner && len(members) > 1 {
+		return ErrOwnerCannotLeave
+	}
+
+	endpoint := fmt.Sprintf("/api/v1/group/%d/leave", g.GroupID)
+	resp, err := g.client.doRequest(ctx, "POST", endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("leave group request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var response struct {
+		Result Result `json:"result"`
+	}
+	if err := g.client.parseResponse(resp, &response); err != nil {
+		return fmt.Errorf("failed to parse leave group response: %w", err)
+	}
+
+	if !response.Result.IsSuccess() {
+		return response.Result.ToError()
+	}
+
+	g.mu.Lock()
+	g.info = nil
+	g.mu.Unlock()
+
+	return nil
+}
+
+// Disband deletes the group for all members. Only the owner can disband a group.
+func (g *Group) Disband(ctx context.Context) error {
+	if err := g.checkPermission(ctx, ActionDisband, ""); err != nil {
+		return err
+	}
+
+	endpoint := fmt.Sprintf("/api/v1/group/%d", g.GroupID)
+	resp, err := g.client.doRequest(ctx, "DELETE", endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("disband group request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var response struct {
+		Result Result `json:"result"`
+	}
+	if err := g.client.parseResponse(resp, &response); err != nil {
+		return fmt.Errorf("failed to parse disband group response: %w", err)
+	}
+
+	if !response.Result.IsSuccess() {
+		return response.Result.ToError()
+	}
+
+	g.mu.Lock()
+	g.info = nil
+	g.mu.Unlock()
+
+	return nil
+}
+
+// TransferOwnership hands the group over to another member and demotes the current owner to Manager.
+// The member list is checked afterwards to make sure exactly one Owner remains.
+func (g *Group) TransferOwnership(ctx context.Context, newOwner string) error {
+	self, err := g.client.selfUsername(ctx)
+	if err != nil {
+		return err
+	}
+	if newOwner == self {
+		return fmt.Errorf("%w: %s already owns the group", ErrOwnershipTransfer, newOwner)
+	}
+	if err := g.checkPermission(ctx, ActionTransferOwnership, newOwner); err != nil {
+		return err
+	}
+
+	members, err := g.GetMembers(ctx)
+	if err != nil {
+		return err
+	}
+	if role, ok := memberRole(members, self); !ok || role != Owner {
+		return ErrPermissionDenied
+	}
+	previousRole, ok := memberRole(members, newOwner)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNotGroupMember, newOwner)
+	}
+
+	// Promote the new owner first so the group is never left without an owner
+	if err := g.SetMemberRole(ctx, newOwner, Owner); err != nil {
+		return fmt.Errorf("failed to promote new owner: %w", err)
+	}
+	if err := g.SetMemberRole(ctx, self, Manager); err != nil {
+		// Roll back the promotion so there is still exactly one owner
+		if rollbackErr := g.SetMemberRole(ctx, newOwner, previousRole); rollbackErr != nil {
+			return fmt.Errorf("%w: failed to demote previous owner (%v) and to roll back (%v)", ErrOwnershipTransfer, err, rollbackErr)
+		}
+		return fmt.Errorf("failed to demote previous owner: %w", err)
+	}
+
+	// Verify the result against the server
+	members, err = g.GetMembers(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to verify ownership transfer: %w", err)
+	}
+	owners := 0
+	for _, m := range members {
+		if GroupMemberType(m.Type) == Owner {
+			owners++
+		}
+	}
+	if role, _ := memberRole(members, newOwner); owners != 1 || role != Owner {
+		return fmt.Errorf("%w: expected %s to be the only owner", ErrOwnershipTransfer, newOwner)
+	}
+
+	g.updateMembers(func([]GroupMember) []GroupMember {
+		return members
+	})
+
+	return nil
+}
+
+// memberRole looks up the role of username in a member list
+func memberRole(members []GroupMember, username string) (GroupMemberType, bool) {
+	for _, m := range members {
+		if m.Name == username {
+			return GroupMemberType(m.Type), true
+		}
+	}
+	return Member, false
+}
@@ -0,0 +1,168 @@
+package stealthim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// BatchOptions holds options for batch group membership operations
+type BatchOptions struct {
+	Concurrency int           // Maximum number of requests in flight
+	Interval    time.Duration // Minimum time between the start of two requests, 0 for no rate limit
+}
+
+// DefaultBatchOptions returns default options for batch operations
+func DefaultBatchOptions() *BatchOptions {
+	return &BatchOptions{
+		Concurrency: 4,
+		Interval:    50 * time.Millisecond,
+	}
+}
+
+// BatchFailure records why an operation failed for one user
+type BatchFailure struct {
+	Username string
+	Err      error
+}
+
+// BatchReport is the per-user outcome of a batch operation
+type BatchReport struct {
+	Succeeded      []string
+	AlreadyMembers []string               // Users that were already members, only filled by BatchInvite
+	Failed         map[int][]BatchFailure // Failures by StealthError code, 0 for errors without a code
+}
+
+// FailedCount returns the number of users the operation failed for
+func (r *BatchReport) FailedCount() int {
+	n := 0
+	for _, failures := range r.Failed {
+		n += len(failures)
+	}
+	return n
+}
+
+// Err returns an error summarizing the failures, or nil if there were none
+func (r *BatchReport) Err() error {
+	if n := r.FailedCount(); n > 0 {
+		return fmt.Errorf("batch operation failed for %d users", n)
+	}
+	return nil
+}
+
+// BatchInvite invites users to the group. Users that are already members are reported
+// separately and not invited again. If the member list cannot be loaded, every user fails.
+func (g *Group) BatchInvite(ctx context.Context, usernames []string, opts *BatchOptions) *BatchReport {
+	members, err := g.GetMembers(ctx)
+	if err != nil {
+		return runBatch(ctx, usernames, opts, func(ctx context.Context, username string) error {
+			return err
+		})
+	}
+
+	var invite, already []string
+	for _, username := range usernames {
+		if _, ok := memberRole(members, username); ok {
+			already = append(already, username)
+		} else {
+			invite = append(invite, username)
+		}
+	}
+	report := runBatch(ctx, invite, opts, func(ctx context.Context, username string) error {
+		return g.Invite(ctx, username)
+	})
+	report.AlreadyMembers = already
+	sort.Strings(report.AlreadyMembers)
+	return report
+}
+
+// BatchKick removes users from the group. The key of an E2EEGroup is not rotated, call RotateKey afterwards.
+func (g *Group) BatchKick(ctx context.Context, usernames []string, opts *BatchOptions) *BatchReport {
+	return runBatch(ctx, usernames, opts, func(ctx context.Context, username string) error {
+		return g.Kick(ctx, username)
+	})
+}
+
+// BatchSetRole assigns roles to several members of the group
+func (g *Group) BatchSetRole(ctx context.Context, roles map[string]GroupMemberType, opts *BatchOptions) *BatchReport {
+	usernames := make([]string, 0, len(roles))
+	for username := range roles {
+		usernames = append(usernames, username)
+	}
+	sort.Strings(usernames)
+
+	return runBatch(ctx, usernames, opts, func(ctx context.Context, username string) error {
+		return g.SetMemberRole(ctx, username, roles[username])
+	})
+}
+
+// runBatch runs op for every user with bounded concurrency and rate limiting
+func runBatch(ctx context.Context, usernames []string, opts *BatchOptions, op func(ctx context.Context, username string) error) *BatchReport {
+	if opts == nil {
+		opts = DefaultBatchOptions()
+	}
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	var ticker *time.Ticker
+	if opts.Interval > 0 {
+		ticker = time.NewTicker(opts.Interval)
+		defer ticker.Stop()
+	}
+
+	report := &BatchReport{Failed: make(map[int][]BatchFailure)}
+	var mu sync.Mutex
+	record := func(username string, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		var stealthErr *StealthError
+		switch {
+		case err == nil:
+			report.Succeeded = append(report.Succeeded, username)
+		case errors.As(err, &stealthErr):
+			report.Failed[stealthErr.Code] = append(report.Failed[stealthErr.Code], BatchFailure{Username: username, Err: err})
+		default:
+			report.Failed[0] = append(report.Failed[0], BatchFailure{Username: username, Err: err})
+		}
+	}
+
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for username := range jobs {
+				if err := ctx.Err(); err != nil {
+					record(username, err)
+					continue
+				}
+				record(username, op(ctx, username))
+			}
+		}()
+	}
+
+	for _, username := range usernames {
+		if ticker != nil && ctx.Err() == nil {
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+			}
+		}
+		jobs <- username
+	}
+	close(jobs)
+	wg.Wait()
+
+	// Keep the report stable regardless of completion order
+	sort.Strings(report.Succeeded)
+	for _, failures := range report.Failed {
+		sort.Slice(failures, func(i, j int) bool { return failures[i].Username < failures[j].Username })
+	}
+	return report
+}
@@ -0,0 +1,138 @@
+package stealthim
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestGroupBatchInvite tests the Group.BatchInvite method
+func TestGroupBatchInvite(t *testing.T) {
+	fs := newFakeServer(t)
+	owner := fs.login(t, "alice")
+	fs.login(t, "bob")
+	fs.login(t, "carol")
+	fs.login(t, "dave")
+	group := fs.createGroup(t, owner, "Team", "bob")
+
+	report := group.BatchInvite(context.Background(), []string{"bob", "carol", "dave", "nobody"}, &BatchOptions{Concurrency: 2})
+	if len(report.Succeeded) != 2 || report.Succeeded[0] != "carol" || report.Succeeded[1] != "dave" {
+		t.Errorf("Unexpected successes: %v", report.Succeeded)
+	}
+	if len(report.AlreadyMembers) != 1 || report.AlreadyMembers[0] != "bob" {
+		t.Errorf("Unexpected already-members: %v", report.AlreadyMembers)
+	}
+	failures := report.Failed[fakeCodeUserNotFound]
+	if len(failures) != 1 || failures[0].Username != "nobody" || report.FailedCount() != 1 {
+		t.Errorf("Unexpected failures: %v", report.Failed)
+	}
+	if report.Err() == nil {
+		t.Error("Expected Err to report the failure")
+	}
+}
+
+// TestGroupBatchKick tests the Group.BatchKick method
+func TestGroupBatchKick(t *testing.T) {
+	fs := newFakeServer(t)
+	owner := fs.login(t, "alice")
+	fs.login(t, "bob")
+	fs.login(t, "carol")
+	group := fs.createGroup(t, owner, "Team", "bob", "carol")
+
+	report := group.BatchKick(context.Background(), []string{"bob", "carol", "dave"}, nil)
+	if len(report.Succeeded) != 2 {
+		t.Errorf("Unexpected successes: %v", report.Succeeded)
+	}
+	if len(report.Failed[fakeCodeNotGroupMember]) != 1 {
+		t.Errorf("Unexpected failures: %v", report.Failed)
+	}
+
+	members, err := group.GetMembers(context.Background())
+	if err != nil {
+		t.Fatalf("GetMembers failed: %v", err)
+	}
+	if len(members) != 1 {
+		t.Errorf("Expected only the owner to remain, got %v", members)
+	}
+}
+
+// TestGroupBatchSetRole tests the Group.BatchSetRole method
+func TestGroupBatchSetRole(t *testing.T) {
+	fs := newFakeServer(t)
+	owner := fs.login(t, "alice")
+	fs.login(t, "bob")
+	fs.login(t, "carol")
+	group := fs.createGroup(t, owner, "Team", "bob", "carol")
+
+	report := group.BatchSetRole(context.Background(), map[string]GroupMemberType{"bob": Manager, "carol": Manager}, nil)
+	if err := report.Err(); err != nil {
+		t.Fatalf("BatchSetRole failed: %v (%v)", err, report.Failed)
+	}
+
+	members, err := group.GetMembers(context.Background())
+	if err != nil {
+		t.Fatalf("GetMembers failed: %v", err)
+	}
+	for _, name := range []string{"bob", "carol"} {
+		if role, _ := memberRole(members, name); role != Manager {
+			t.Errorf("Expected %s to be a manager, got %d", name, role)
+		}
+	}
+}
+
+// TestGroupBatchLimits tests that batch operations respect the concurrency and rate limits
+func TestGroupBatchLimits(t *testing.T) {
+	fs := newFakeServer(t)
+	owner := fs.login(t, "alice")
+	usernames := []string{"u01", "u02", "u03", "u04", "u05", "u06"}
+	for _, name := range usernames {
+		fs.login(t, name)
+	}
+	group := fs.createGroup(t, owner, "Team")
+
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	owner.client.Use(func(ctx context.Context, call *Call, next Invoker) (*Reply, error) {
+		mu.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		defer func() {
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+		}()
+		return next(ctx, call)
+	})
+
+	interval := 10 * time.Millisecond
+	start := time.Now()
+	report := group.BatchInvite(context.Background(), usernames, &BatchOptions{Concurrency: 2, Interval: interval})
+	if err := report.Err(); err != nil {
+		t.Fatalf("BatchInvite failed: %v", err)
+	}
+	if maxInFlight > 2 {
+		t.Errorf("Expected at most 2 requests in flight, got %d", maxInFlight)
+	}
+	if elapsed := time.Since(start); elapsed < time.Duration(len(usernames))*interval {
+		t.Errorf("Expected rate limiting to take at least %v, took %v", time.Duration(len(usernames))*interval, elapsed)
+	}
+}
+
+// TestGroupBatchCanceled tests that canceled batches report the remaining users as failed
+func TestGroupBatchCanceled(t *testing.T) {
+	fs := newFakeServer(t)
+	owner := fs.login(t, "alice")
+	group := fs.createGroup(t, owner, "Team")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	report := group.BatchInvite(ctx, []string{"bob", "carol"}, nil)
+	if len(report.Failed[0]) != 2 || len(report.Succeeded) != 0 {
+		t.Errorf("Expected all users to fail, got %+v", report)
+	}
+}
@@ -0,0 +1,189 @@
+package stealthim
+
+import (
+	"context"
+	"fmt"
+)
+
+// GroupInfo holds cached group metadata for display, such as a group header
+type GroupInfo struct {
+	GroupID     int64
+	Name        string
+	Owner       string
+	MemberCount int
+	CreateTime  string
+	Role        GroupMemberType // Role of the current user, only meaningful if IsMember is true
+	IsMember    bool
+	Members     []GroupMember
+}
+
+// JoinPolicy describes how users can join a group
+type JoinPolicy string
+
+// Join policies
+const (
+	JoinPolicyOpen       JoinPolicy = "open"     // Anyone can join without a password
+	JoinPolicyPassword   JoinPolicy = "password" // Join requires the group password
+	JoinPolicyInviteOnly JoinPolicy = "invite"   // Users can only be invited by members
+)
+
+// GroupPublicInfo is the public profile of a group, visible to non-members,
+// for example to show a preview before calling Join
+type GroupPublicInfo struct {
+	GroupID     int64
+	Name        string
+	CreateTime  string
+	MemberCount int
+	JoinPolicy  JoinPolicy
+	Owner       string        // Empty if the server does not list members publicly
+	Members     []GroupMember // Publicly listed members, may be empty
+}
+
+// groupPublicResponse is the body of the public group endpoint
+type groupPublicResponse struct {
+	Result      Result        `json:"result"`
+	Name        string        `json:"name"`
+	CreateTime  string        `json:"create_time"`
+	MemberCount int           `json:"member_count"`
+	JoinPolicy  JoinPolicy    `json:"join_policy"`
+	Members     []GroupMember `json:"members"`
+}
+
+// publicInfo converts the response to a GroupPublicInfo, filling in values older servers do not report
+func (r *groupPublicResponse) publicInfo(groupID int64) *GroupPublicInfo {
+	info := &GroupPublicInfo{
+		GroupID:     groupID,
+		Name:        r.Name,
+		CreateTime:  r.CreateTime,
+		MemberCount: r.MemberCount,
+		JoinPolicy:  r.JoinPolicy,
+		Members:     r.Members,
+	}
+	if info.MemberCount == 0 {
+		info.MemberCount = len(r.Members)
+	}
+	// v1 servers without join policies protect every group with a password
+	if info.JoinPolicy == "" {
+		info.JoinPolicy = JoinPolicyPassword
+	}
+	for _, m := range r.Members {
+		if GroupMemberType(m.Type) == Owner {
+			info.Owner = m.Name
+		}
+	}
+	return info
+}
+
+// fetchPublic retrieves the public group profile
+func (g *Group) fetchPublic(ctx context.Context) (*groupPublicResponse, error) {
+	endpoint := fmt.Sprintf("/api/v1/group/%d/public", g.GroupID)
+	resp, err := g.client.doRequest(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("get group info request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var response groupPublicResponse
+	if err := g.client.parseResponse(resp, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse get group info response: %w", err)
+	}
+
+	if !response.Result.IsSuccess() {
+		return nil, response.Result.ToError()
+	}
+
+	return &response, nil
+}
+
+// Info returns the cached group metadata, loading it from the server on first use
+func (g *Group) Info(ctx context.Context) (*GroupInfo, error) {
+	g.mu.Lock()
+	info := g.info
+	g.mu.Unlock()
+	if info != nil {
+		return info.clone(), nil
+	}
+	return g.Refresh(ctx)
+}
+
+// Refresh reloads the group metadata from the server and updates the cache
+func (g *Group) Refresh(ctx context.Context) (*GroupInfo, error) {
+	self, err := g.client.selfUsername(ctx)
+	if err != nil {
+		return nil, err
+	}
+	public, err := g.fetchPublic(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// The full member list is only visible to members, fall back to the public one when
+	// the server refuses it. Transport errors are returned.
+	members, err := g.GetMembers(ctx)
+	full := err == nil
+	switch {
+	case isServerRejection(err):
+		members = public.Members
+	case err != nil:
+		return nil, err
+	}
+
+	info := &GroupInfo{
+		GroupID:    g.GroupID,
+		Name:       public.Name,
+		CreateTime: public.CreateTime,
+	}
+	info.setMembers(members, self)
+	// The public member list may be partial or empty, the reported count is more accurate
+	if !full {
+		info.MemberCount = public.publicInfo(g.GroupID).MemberCount
+	}
+
+	g.mu.Lock()
+	g.info = info
+	g.mu.Unlock()
+
+	return info.clone(), nil
+}
+
+// setMembers replaces the member list and derives owner, member count and own role from it
+func (i *GroupInfo) setMembers(members []GroupMember, self string) {
+	i.Members = members
+	i.MemberCount = len(members)
+	i.Owner = ""
+	i.IsMember = false
+	i.Role = Member
+	for _, m := range members {
+		if GroupMemberType(m.Type) == Owner {
+			i.Owner = m.Name
+		}
+		if self != "" && m.Name == self {
+			i.IsMember = true
+			i.Role = GroupMemberType(m.Type)
+		}
+	}
+}
+
+// clone returns a copy of the info that callers may modify freely
+func (i *GroupInfo) clone() *GroupInfo {
+	c := *i
+	c.Members = append([]GroupMember(nil), i.Members...)
+	return &c
+}
+
+// updateInfo applies fn to the cached metadata if it has been loaded
+func (g *Group) updateInfo(fn func(info *GroupInfo)) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.info != nil {
+		fn(g.info)
+	}
+}
+
+// updateMembers applies fn to a copy of the cached member list and stores the result
+func (g *Group) updateMembers(fn func(members []GroupMember) []GroupMember) {
+	g.updateInfo(func(info *GroupInfo) {
+		members := fn(append([]GroupMember(nil), info.Members...))
+		info.setMembers(members, g.client.currentUsername())
+	})
+}
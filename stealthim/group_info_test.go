@@ -0,0 +1,190 @@
+package stealthim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// countRequests installs an interceptor that counts the calls made by client
+func countRequests(client *Client) *int {
+	count := 0
+	client.Use(func(ctx context.Context, call *Call, next Invoker) (*Reply, error) {
+		count++
+		return next(ctx, call)
+	})
+	return &count
+}
+
+// TestGroupInfo tests that Group.Info loads and caches the group metadata
+func TestGroupInfo(t *testing.T) {
+	fs := newFakeServer(t)
+	owner := fs.login(t, "alice")
+	fs.login(t, "bob")
+	group := fs.createGroup(t, owner, "Team", "bob")
+
+	ctx := context.Background()
+	info, err := group.Info(ctx)
+	if err != nil {
+		t.Fatalf("Info failed: %v", err)
+	}
+	if info.Name != "Team" || info.Owner != "alice" || info.MemberCount != 2 || info.CreateTime == "" {
+		t.Errorf("Unexpected group info: %+v", info)
+	}
+	if !info.IsMember || info.Role != Owner {
+		t.Errorf("Expected to be the owner, got %+v", info)
+	}
+
+	requests := countRequests(owner.client)
+	if _, err := group.Info(ctx); err != nil {
+		t.Fatalf("Info failed: %v", err)
+	}
+	if *requests != 0 {
+		t.Errorf("Expected cached info without requests, got %d requests", *requests)
+	}
+}
+
+// TestGroupInfoCacheUpdates tests that group operations update the cached metadata
+func TestGroupInfoCacheUpdates(t *testing.T) {
+	fs := newFakeServer(t)
+	owner := fs.login(t, "alice")
+	fs.login(t, "bob")
+	fs.login(t, "carol")
+	group := fs.createGroup(t, owner, "Team", "bob")
+
+	ctx := context.Background()
+	if _, err := group.Info(ctx); err != nil {
+		t.Fatalf("Info failed: %v", err)
+	}
+
+	if err := group.ChangeName(ctx, "Renamed"); err != nil {
+		t.Fatalf("ChangeName failed: %v", err)
+	}
+	if err := group.Invite(ctx, "carol"); err != nil {
+		t.Fatalf("Invite failed: %v", err)
+	}
+	if err := group.SetMemberRole(ctx, "carol", Manager); err != nil {
+		t.Fatalf("SetMemberRole failed: %v", err)
+	}
+	if err := group.Kick(ctx, "bob"); err != nil {
+		t.Fatalf("Kick failed: %v", err)
+	}
+
+	info, err := group.Info(ctx)
+	if err != nil {
+		t.Fatalf("Info failed: %v", err)
+	}
+	if info.Name != "Renamed" || info.MemberCount != 2 {
+		t.Errorf("Unexpected cached info: %+v", info)
+	}
+	for _, m := range info.Members {
+		if m.Name == "bob" {
+			t.Error("Expected kicked member to be removed from the cache")
+		}
+		if m.Name == "carol" && GroupMemberType(m.Type) != Manager {
+			t.Errorf("Expected carol to be a manager, got %d", m.Type)
+		}
+	}
+
+	refreshed, err := group.Refresh(ctx)
+	if err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	if refreshed.Name != info.Name || refreshed.MemberCount != info.MemberCount {
+		t.Errorf("Cached info %+v differs from server state %+v", info, refreshed)
+	}
+}
+
+// TestGroupInfoNonMember tests that non-members still get the public metadata
+func TestGroupInfoNonMember(t *testing.T) {
+	fs := newFakeServer(t)
+	owner := fs.login(t, "alice")
+	outsider := fs.login(t, "mallory")
+	group := fs.createGroup(t, owner, "Team")
+
+	view := &Group{client: outsider.client, GroupID: group.GroupID}
+	info, err := view.Info(context.Background())
+	if err != nil {
+		t.Fatalf("Info failed: %v", err)
+	}
+	if info.IsMember || info.Owner != "alice" || info.Name != "Team" {
+		t.Errorf("Unexpected info for non-member: %+v", info)
+	}
+
+	// Servers that do not list members publicly still report how many there are
+	fs.mu.Lock()
+	fs.noPublicMembers = true
+	fs.mu.Unlock()
+	if info, err = view.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	if info.MemberCount != 1 || len(info.Members) != 0 {
+		t.Errorf("Expected the public member count without members, got %+v", info)
+	}
+}
+
+// TestGroupRefreshMembersError tests that failures other than missing access are returned
+func TestGroupRefreshMembersError(t *testing.T) {
+	fs := newFakeServer(t)
+	owner := fs.login(t, "alice")
+	group := fs.createGroup(t, owner, "Team")
+
+	outage := errors.New("connection reset")
+	members := fmt.Sprintf("/api/v1/group/%d", group.GroupID)
+	owner.client.Use(func(ctx context.Context, call *Call, next Invoker) (*Reply, error) {
+		if call.Endpoint == members {
+			return nil, outage
+		}
+		return next(ctx, call)
+	})
+
+	if _, err := group.Refresh(context.Background()); !errors.Is(err, outage) {
+		t.Errorf("Expected the member list failure, got %v", err)
+	}
+}
+
+// TestGroupGetInfoPreview tests that Group.GetInfo returns the public profile to non-members
+func TestGroupGetInfoPreview(t *testing.T) {
+	fs := newFakeServer(t)
+	owner := fs.login(t, "alice")
+	outsider := fs.login(t, "mallory")
+	fs.login(t, "bob")
+	group := fs.createGroup(t, owner, "Team", "bob")
+
+	ctx := context.Background()
+	view := &Group{client: outsider.client, GroupID: group.GroupID}
+	info, err := view.GetInfo(ctx)
+	if err != nil {
+		t.Fatalf("GetInfo failed: %v", err)
+	}
+	if info.GroupID != group.GroupID || info.Name != "Team" || info.MemberCount != 2 || info.Owner != "alice" {
+		t.Errorf("Unexpected public info: %+v", info)
+	}
+	if info.JoinPolicy != JoinPolicyOpen {
+		t.Errorf("Expected an open group, got %q", info.JoinPolicy)
+	}
+
+	if err := group.ChangePassword(ctx, "secret"); err != nil {
+		t.Fatalf("ChangePassword failed: %v", err)
+	}
+	info, err = view.GetInfo(ctx)
+	if err != nil {
+		t.Fatalf("GetInfo failed: %v", err)
+	}
+	if info.JoinPolicy != JoinPolicyPassword {
+		t.Errorf("Expected a password-protected group, got %q", info.JoinPolicy)
+	}
+}
+
+// TestGroupPublicInfoDefaults tests the fallbacks for servers that report only name and members
+func TestGroupPublicInfoDefaults(t *testing.T) {
+	resp := &groupPublicResponse{
+		Name:    "Team",
+		Members: []GroupMember{{Name: "alice", Type: int(Owner)}, {Name: "bob", Type: int(Member)}},
+	}
+	info := resp.publicInfo(7)
+	if info.MemberCount != 2 || info.Owner != "alice" || info.JoinPolicy != JoinPolicyPassword {
+		t.Errorf("Unexpected defaults: %+v", info)
+	}
+}
@@ -0,0 +1,224 @@
+package stealthim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// ErrInvalidSpec is returned when a group spec cannot be reconciled
+var ErrInvalidSpec = errors.New("invalid group spec")
+
+// GroupSpec is the desired state of a group, for example loaded from a YAML or JSON file
+type GroupSpec struct {
+	Name          string                     `json:"name" yaml:"name"`                                         // Empty to leave the name unchanged
+	Password      string                     `json:"password,omitempty" yaml:"password,omitempty"`             // New password, only set when ForcePassword is true
+	ForcePassword bool                       `json:"force_password,omitempty" yaml:"force_password,omitempty"` // Set Password even if it may be unchanged
+	Members       map[string]GroupMemberType `json:"members" yaml:"members"`                                   // Members and their roles, must include the owner
+}
+
+// PlanActionType is the kind of change a plan action makes
+type PlanActionType int
+
+// Plan action types
+const (
+	PlanChangeName PlanActionType = iota
+	PlanChangePassword
+	PlanInvite
+	PlanSetRole
+	PlanKick
+)
+
+// PlanAction is a single change needed to bring a group to its desired state
+type PlanAction struct {
+	Type     PlanActionType
+	Username string          // Target user for PlanInvite, PlanSetRole and PlanKick
+	Role     GroupMemberType // New role for PlanSetRole
+	Name     string          // New group name for PlanChangeName
+
+	password string // New password for PlanChangePassword, kept out of String
+}
+
+// String describes the action
+func (a PlanAction) String() string {
+	switch a.Type {
+	case PlanChangeName:
+		return fmt.Sprintf("change name to %q", a.Name)
+	case PlanChangePassword:
+		return "change password"
+	case PlanInvite:
+		return fmt.Sprintf("invite %s", a.Username)
+	case PlanSetRole:
+		return fmt.Sprintf("set role of %s to %s", a.Username, a.Role)
+	case PlanKick:
+		return fmt.Sprintf("kick %s", a.Username)
+	default:
+		return fmt.Sprintf("action %d", int(a.Type))
+	}
+}
+
+// Plan is the ordered list of actions that reconciles a group with a GroupSpec
+type Plan struct {
+	GroupID int64
+	Actions []PlanAction
+}
+
+// Empty reports whether the group already matches the spec
+func (p *Plan) Empty() bool {
+	return len(p.Actions) == 0
+}
+
+// String returns a human readable description of the plan, one action per line
+func (p *Plan) String() string {
+	if p.Empty() {
+		return fmt.Sprintf("group %d: no changes\n", p.GroupID)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "group %d: %d changes\n", p.GroupID, len(p.Actions))
+	for _, action := range p.Actions {
+		fmt.Fprintf(&b, "  - %s\n", action)
+	}
+	return b.String()
+}
+
+// ReconcileOptions holds options for Group.Reconcile
+type ReconcileOptions struct {
+	DryRun bool      // Only compute the plan, do not change the group
+	Out    io.Writer // If set, the plan is written here before it is applied
+}
+
+// Plan compares the live group with spec and returns the actions needed to match it.
+//
+// Ownership is never changed by a plan: the spec must list the current owner as Owner and
+// nobody else, use TransferOwnership to hand a group over. The current password cannot be
+// read back and compared, so a password change is only planned when the spec sets
+// ForcePassword; leave it unset for runs that should converge to an empty plan.
+func (g *Group) Plan(ctx context.Context, spec *GroupSpec) (*Plan, error) {
+	info, err := g.Refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for username, role := range spec.Members {
+		if role != Member && role != Manager && role != Owner {
+			return nil, fmt.Errorf("%w: unknown role %d for %s", ErrInvalidSpec, int(role), username)
+		}
+		if role == Owner && username != info.Owner {
+			return nil, fmt.Errorf("%w: %s is not the owner, use TransferOwnership", ErrInvalidSpec, username)
+		}
+	}
+	if role, ok := spec.Members[info.Owner]; !ok || role != Owner {
+		return nil, fmt.Errorf("%w: owner %s must be listed with role %s", ErrInvalidSpec, info.Owner, Owner)
+	}
+
+	plan := &Plan{GroupID: g.GroupID}
+	if spec.Name != "" && spec.Name != info.Name {
+		plan.Actions = append(plan.Actions, PlanAction{Type: PlanChangeName, Name: spec.Name})
+	}
+	if spec.ForcePassword {
+		if spec.Password == "" {
+			return nil, fmt.Errorf("%w: force_password needs a password", ErrInvalidSpec)
+		}
+		plan.Actions = append(plan.Actions, PlanAction{Type: PlanChangePassword, password: spec.Password})
+	}
+
+	usernames := make([]string, 0, len(spec.Members))
+	for username := range spec.Members {
+		usernames = append(usernames, username)
+	}
+	sort.Strings(usernames)
+
+	// Invite first, then fix up roles, so new members can be promoted in the same run
+	var roleChanges []PlanAction
+	for _, username := range usernames {
+		role := spec.Members[username]
+		current, ok := memberRole(info.Members, username)
+		if !ok {
+			plan.Actions = append(plan.Actions, PlanAction{Type: PlanInvite, Username: username})
+			current = Member
+		}
+		if current != role {
+			roleChanges = append(roleChanges, PlanAction{Type: PlanSetRole, Username: username, Role: role})
+		}
+	}
+	plan.Actions = append(plan.Actions, roleChanges...)
+
+	var kicks []string
+	for _, m := range info.Members {
+		if _, ok := spec.Members[m.Name]; !ok {
+			kicks = append(kicks, m.Name)
+		}
+	}
+	sort.Strings(kicks)
+	for _, username := range kicks {
+		plan.Actions = append(plan.Actions, PlanAction{Type: PlanKick, Username: username})
+	}
+
+	return plan, nil
+}
+
+// Apply carries out a plan. Invites and kicks that the current member list shows as done
+// already, such as inviting a user who has joined since the plan was made, are skipped,
+// so a plan can safely be applied again.
+func (g *Group) Apply(ctx context.Context, plan *Plan) error {
+	members, err := g.GetMembers(ctx)
+	if err != nil {
+		return err
+	}
+	for _, action := range plan.Actions {
+		_, isMember := memberRole(members, action.Username)
+		var err error
+		switch action.Type {
+		case PlanChangeName:
+			err = g.ChangeName(ctx, action.Name)
+		case PlanChangePassword:
+			err = g.ChangePassword(ctx, action.password)
+		case PlanInvite:
+			if !isMember {
+				err = g.Invite(ctx, action.Username)
+			}
+		case PlanSetRole:
+			err = g.SetMemberRole(ctx, action.Username, action.Role)
+		case PlanKick:
+			if isMember {
+				err = g.Kick(ctx, action.Username)
+			}
+		default:
+			err = fmt.Errorf("unknown plan action %d", int(action.Type))
+		}
+		if err != nil {
+			return fmt.Errorf("failed to %s: %w", action, err)
+		}
+	}
+	return nil
+}
+
+// Reconcile brings the group to the state described by spec and returns the plan it carried out.
+// With DryRun set the plan is only computed and written to Out. Kicks do not rotate the key of an
+// E2EEGroup, call RotateKey afterwards.
+func (g *Group) Reconcile(ctx context.Context, spec *GroupSpec, opts *ReconcileOptions) (*Plan, error) {
+	if opts == nil {
+		opts = &ReconcileOptions{}
+	}
+
+	plan, err := g.Plan(ctx, spec)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Out != nil {
+		if _, err := io.WriteString(opts.Out, plan.String()); err != nil {
+			return nil, fmt.Errorf("failed to write plan: %w", err)
+		}
+	}
+	if opts.DryRun {
+		return plan, nil
+	}
+
+	if err := g.Apply(ctx, plan); err != nil {
+		return plan, err
+	}
+	return plan, nil
+}
@@ -0,0 +1,129 @@
+package stealthim
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestGroupReconcile tests that Group.Reconcile plans, dry-runs and applies a group spec
+func TestGroupReconcile(t *testing.T) {
+	fs := newFakeServer(t)
+	owner := fs.login(t, "alice")
+	fs.login(t, "bob")
+	fs.login(t, "carol")
+	fs.login(t, "dave")
+	group := fs.createGroup(t, owner, "Team", "bob", "carol")
+
+	spec := &GroupSpec{
+		Name: "Platform",
+		Members: map[string]GroupMemberType{
+			"alice": Owner,
+			"bob":   Manager,
+			"dave":  Manager,
+		},
+	}
+	ctx := context.Background()
+
+	var out strings.Builder
+	plan, err := group.Reconcile(ctx, spec, &ReconcileOptions{DryRun: true, Out: &out})
+	if err != nil {
+		t.Fatalf("Dry run failed: %v", err)
+	}
+	expected := []string{
+		`change name to "Platform"`,
+		"invite dave",
+		"set role of bob to manager",
+		"set role of dave to manager",
+		"kick carol",
+	}
+	if len(plan.Actions) != len(expected) {
+		t.Fatalf("Expected %d actions, got:\n%s", len(expected), plan)
+	}
+	for i, action := range plan.Actions {
+		if action.String() != expected[i] {
+			t.Errorf("Action %d: expected %q, got %q", i, expected[i], action.String())
+		}
+	}
+	if out.String() != plan.String() {
+		t.Errorf("Expected the plan to be written, got %q", out.String())
+	}
+
+	// The dry run must not have changed anything
+	members, err := group.GetMembers(ctx)
+	if err != nil {
+		t.Fatalf("GetMembers failed: %v", err)
+	}
+	if len(members) != 3 {
+		t.Errorf("Dry run changed the members: %v", members)
+	}
+
+	if _, err := group.Reconcile(ctx, spec, nil); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	plan, err = group.Plan(ctx, spec)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if !plan.Empty() {
+		t.Errorf("Expected no changes after reconciling, got:\n%s", plan)
+	}
+
+	// Applying a stale plan again must succeed
+	stale := &Plan{GroupID: group.GroupID, Actions: []PlanAction{
+		{Type: PlanInvite, Username: "dave"},
+		{Type: PlanKick, Username: "carol"},
+	}}
+	if err := group.Apply(ctx, stale); err != nil {
+		t.Errorf("Expected a stale plan to apply cleanly, got %v", err)
+	}
+}
+
+// TestGroupPlanInvalidSpec tests that Group.Plan rejects specs that would change ownership
+// or are incomplete
+func TestGroupPlanInvalidSpec(t *testing.T) {
+	fs := newFakeServer(t)
+	owner := fs.login(t, "alice")
+	fs.login(t, "bob")
+	group := fs.createGroup(t, owner, "Team", "bob")
+
+	specs := []*GroupSpec{
+		{Members: map[string]GroupMemberType{"bob": Owner}},
+		{Members: map[string]GroupMemberType{"alice": Owner, "bob": Owner}},
+		{Members: map[string]GroupMemberType{"alice": Manager}},
+		{Members: map[string]GroupMemberType{"alice": Owner, "bob": 7}},
+		{ForcePassword: true, Members: map[string]GroupMemberType{"alice": Owner, "bob": Member}},
+	}
+	for i, spec := range specs {
+		if _, err := group.Plan(context.Background(), spec); !errors.Is(err, ErrInvalidSpec) {
+			t.Errorf("Spec %d: expected ErrInvalidSpec, got %v", i, err)
+		}
+	}
+}
+
+// TestGroupPlanPassword tests that the password is only changed when the spec forces it
+func TestGroupPlanPassword(t *testing.T) {
+	fs := newFakeServer(t)
+	owner := fs.login(t, "alice")
+	group := fs.createGroup(t, owner, "Team")
+	ctx := context.Background()
+
+	spec := &GroupSpec{Password: "secret", Members: map[string]GroupMemberType{"alice": Owner}}
+	plan, err := group.Plan(ctx, spec)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if !plan.Empty() {
+		t.Errorf("Expected a converged group without ForcePassword, got:\n%s", plan)
+	}
+
+	spec.ForcePassword = true
+	plan, err = group.Plan(ctx, spec)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if len(plan.Actions) != 1 || plan.Actions[0].Type != PlanChangePassword || strings.Contains(plan.String(), "secret") {
+		t.Errorf("Expected a single password change that hides the password, got:\n%s", plan)
+	}
+}
@@ -2,6 +2,7 @@ package stealthim
 
 import (
 	"context"
+	"errors"
 	"os"
 	"testing"
 	"time"
@@ -412,4 +413,125 @@ func TestGroupChangePassword(t *testing.T) {
 	if err != nil {
 		t.Errorf("Failed to change group password: %v", err)
 	}
-}
\ No newline at end of file
+}
+
+// TestGroupLeave tests the Group.Leave method
+func TestGroupLeave(t *testing.T) {
+	fs := newFakeServer(t)
+	owner := fs.login(t, "alice")
+	member := fs.login(t, "bob")
+	group := fs.createGroup(t, owner, "Team", "bob")
+
+	ctx := context.Background()
+	if err := group.Leave(ctx); err != ErrOwnerCannotLeave {
+		t.Errorf("Expected ErrOwnerCannotLeave, got %v", err)
+	}
+
+	if err := member.OpenGroup(group.GroupID).Leave(ctx); err != nil {
+		t.Fatalf("Failed to leave group: %v", err)
+	}
+	members, err := group.GetMembers(ctx)
+	if err != nil {
+		t.Fatalf("Failed to get members: %v", err)
+	}
+	if len(members) != 1 || members[0].Name != "alice" {
+		t.Errorf("Expected only the owner to remain, got %v", members)
+	}
+}
+
+// TestGroupDisband tests the Group.Disband method
+func TestGroupDisband(t *testing.T) {
+	fs := newFakeServer(t)
+	owner := fs.login(t, "alice")
+	member := fs.login(t, "bob")
+	group := fs.createGroup(t, owner, "Team", "bob")
+
+	ctx := context.Background()
+	if err := member.OpenGroup(group.GroupID).Disband(ctx); err == nil {
+		t.Error("Expected a member to be unable to disband the group")
+	}
+	if err := group.Disband(ctx); err != nil {
+		t.Fatalf("Failed to disband group: %v", err)
+	}
+
+	groups, err := owner.GetGroups(ctx)
+	if err != nil {
+		t.Fatalf("Failed to get groups: %v", err)
+	}
+	if len(groups) != 0 {
+		t.Errorf("Expected no groups after disbanding, got %v", groups)
+	}
+}
+
+// TestGroupTransferOwnership tests the Group.TransferOwnership method
+func TestGroupTransferOwnership(t *testing.T) {
+	fs := newFakeServer(t)
+	owner := fs.login(t, "alice")
+	fs.login(t, "bob")
+	group := fs.createGroup(t, owner, "Team", "bob")
+
+	ctx := context.Background()
+	if err := group.TransferOwnership(ctx, "carol"); !errors.Is(err, ErrNotGroupMember) {
+		t.Errorf("Expected ErrNotGroupMember, got %v", err)
+	}
+	if err := group.TransferOwnership(ctx, "bob"); err != nil {
+		t.Fatalf("Failed to transfer ownership: %v", err)
+	}
+
+	members, err := group.GetMembers(ctx)
+	if err != nil {
+		t.Fatalf("Failed to get members: %v", err)
+	}
+	owners := 0
+	for _, m := range members {
+		if GroupMemberType(m.Type) == Owner {
+			owners++
+			if m.Name != "bob" {
+				t.Errorf("Expected bob to be the owner, got %s", m.Name)
+			}
+		}
+		if m.Name == "alice" && GroupMemberType(m.Type) != Manager {
+			t.Errorf("Expected previous owner to become a manager, got %d", m.Type)
+		}
+	}
+	if owners != 1 {
+		t.Errorf("Expected exactly one owner, got %d", owners)
+	}
+
+	if err := group.TransferOwnership(ctx, "bob"); err != ErrPermissionDenied {
+		t.Errorf("Expected ErrPermissionDenied for a non-owner, got %v", err)
+	}
+}
+
+// TestGroupTransferOwnershipRollback tests that a failed demotion rolls back the promotion
+func TestGroupTransferOwnershipRollback(t *testing.T) {
+	fs := newFakeServer(t)
+	owner := fs.login(t, "alice")
+	fs.login(t, "bob")
+	group := fs.createGroup(t, owner, "Team", "bob")
+
+	// Fail the demotion of the current owner
+	owner.client.Use(func(ctx context.Context, call *Call, next Invoker) (*Reply, error) {
+		if body, ok := call.Body.(map[string]any); ok && body["username"] == "alice" && body["type"] == int(Manager) {
+			return &Reply{Result: Result{Code: 900, Msg: "injected"}}, nil
+		}
+		return next(ctx, call)
+	})
+
+	if err := group.TransferOwnership(context.Background(), "bob"); err == nil {
+		t.Fatal("Expected transfer to fail")
+	}
+
+	members, err := group.GetMembers(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to get members: %v", err)
+	}
+	for _, m := range members {
+		if m.Name == "bob" && GroupMemberType(m.Type) != Member {
+			t.Errorf("Expected promotion to be rolled back, bob has role %d", m.Type)
+		}
+		if m.Name == "alice" && GroupMemberType(m.Type) != Owner {
+			t.Errorf("Expected alice to remain owner, got %d", m.Type)
+		}
+	}
+}
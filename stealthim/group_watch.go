@@ -0,0 +1,154 @@
+package stealthim
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"time"
+)
+
+// MemberEventType is the kind of change in a group member list
+type MemberEventType int
+
+// Member event types
+const (
+	MemberJoined MemberEventType = iota
+	MemberLeft
+	MemberRoleChanged
+)
+
+// String returns the name of the event type
+func (t MemberEventType) String() string {
+	switch t {
+	case MemberJoined:
+		return "joined"
+	case MemberLeft:
+		return "left"
+	case MemberRoleChanged:
+		return "role changed"
+	default:
+		return fmt.Sprintf("event %d", int(t))
+	}
+}
+
+// MemberEvent describes a single change in a group member list
+type MemberEvent struct {
+	Type    MemberEventType
+	Member  GroupMember     // Current state of the member, or the last known state for MemberLeft
+	OldRole GroupMemberType // Previous role, only set for MemberRoleChanged
+}
+
+// DiffMembers compares two member list snapshots and returns the changes between them.
+// Joins and role changes follow the order of newMembers, followed by departures in the order of oldMembers.
+func DiffMembers(oldMembers, newMembers []GroupMember) []MemberEvent {
+	previous := make(map[string]GroupMember, len(oldMembers))
+	for _, m := range oldMembers {
+		previous[m.Name] = m
+	}
+
+	var events []MemberEvent
+	current := make(map[string]bool, len(newMembers))
+	for _, m := range newMembers {
+		current[m.Name] = true
+		old, ok := previous[m.Name]
+		switch {
+		case !ok:
+			events = append(events, MemberEvent{Type: MemberJoined, Member: m})
+		case old.Type != m.Type:
+			events = append(events, MemberEvent{Type: MemberRoleChanged, Member: m, OldRole: GroupMemberType(old.Type)})
+		}
+	}
+	for _, m := range oldMembers {
+		if !current[m.Name] {
+			events = append(events, MemberEvent{Type: MemberLeft, Member: m})
+		}
+	}
+	return events
+}
+
+// WatchMembersOptions holds options for watching a group member list
+type WatchMembersOptions struct {
+	Interval time.Duration // Time between two polls, the default is used if it is not positive
+	Jitter   time.Duration // Random extra delay up to this value, spreads out polls of many watchers
+}
+
+// DefaultWatchMembersOptions returns default options for watching group members
+func DefaultWatchMembersOptions() *WatchMembersOptions {
+	return &WatchMembersOptions{
+		Interval: 30 * time.Second,
+		Jitter:   5 * time.Second,
+	}
+}
+
+// WatchMembers polls the group member list and emits an event for every member that joins,
+// leaves or changes role. The server does not push membership changes, so changes are found
+// by diffing snapshots. The first snapshot only sets the baseline.
+//
+// Polling errors are sent on the error channel without stopping the watch; errors are dropped
+// if the previous one has not been read yet. Both channels are closed when ctx is done.
+func (g *Group) WatchMembers(ctx context.Context, opts *WatchMembersOptions) (<-chan MemberEvent, <-chan error) {
+	if opts == nil {
+		opts = DefaultWatchMembersOptions()
+	}
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = DefaultWatchMembersOptions().Interval
+	}
+	eventChan := make(chan MemberEvent)
+	errorChan := make(chan error, 1)
+
+	go func() {
+		defer close(eventChan)
+		defer close(errorChan)
+
+		logger := g.client.logger()
+		var snapshot []GroupMember
+		initialized := false
+
+		for {
+			members, err := g.GetMembers(ctx)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				logger.LogAttrs(ctx, slog.LevelWarn, "member poll failed",
+					slog.Int64("group_id", g.GroupID),
+					slog.Any("error", err),
+				)
+				select {
+				case errorChan <- err:
+				default:
+				}
+			} else {
+				if initialized {
+					for _, event := range DiffMembers(snapshot, members) {
+						select {
+						case eventChan <- event:
+						case <-ctx.Done():
+							return
+						}
+					}
+				}
+				snapshot, initialized = members, true
+				g.updateMembers(func([]GroupMember) []GroupMember {
+					return members
+				})
+			}
+
+			delay := interval
+			if opts.Jitter > 0 {
+				delay += time.Duration(rand.Int63n(int64(opts.Jitter)))
+			}
+			timer := time.NewTimer(delay)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			}
+		}
+	}()
+
+	return eventChan, errorChan
+}
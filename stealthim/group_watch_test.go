@@ -0,0 +1,117 @@
+package stealthim
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestDiffMembers tests the DiffMembers function
+func TestDiffMembers(t *testing.T) {
+	oldMembers := []GroupMember{
+		{Name: "alice", Type: int(Owner)},
+		{Name: "bob", Type: int(Member)},
+		{Name: "carol", Type: int(Member)},
+	}
+	newMembers := []GroupMember{
+		{Name: "alice", Type: int(Owner)},
+		{Name: "bob", Type: int(Manager)},
+		{Name: "dave", Type: int(Member)},
+	}
+
+	events := DiffMembers(oldMembers, newMembers)
+	expected := []MemberEvent{
+		{Type: MemberRoleChanged, Member: GroupMember{Name: "bob", Type: int(Manager)}, OldRole: Member},
+		{Type: MemberJoined, Member: GroupMember{Name: "dave", Type: int(Member)}},
+		{Type: MemberLeft, Member: GroupMember{Name: "carol", Type: int(Member)}},
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("Expected %d events, got %v", len(expected), events)
+	}
+	for i := range expected {
+		if events[i] != expected[i] {
+			t.Errorf("Event %d: expected %+v, got %+v", i, expected[i], events[i])
+		}
+	}
+
+	if events := DiffMembers(newMembers, newMembers); len(events) != 0 {
+		t.Errorf("Expected no events for identical snapshots, got %v", events)
+	}
+}
+
+// TestGroupWatchMembers tests the Group.WatchMembers method
+func TestGroupWatchMembers(t *testing.T) {
+	fs := newFakeServer(t)
+	owner := fs.login(t, "alice")
+	fs.login(t, "bob")
+	fs.login(t, "carol")
+	group := fs.createGroup(t, owner, "Team", "bob")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Signal polls so changes are only made once the baseline is taken
+	polls := make(chan struct{}, 16)
+	owner.client.Use(func(ctx context.Context, call *Call, next Invoker) (*Reply, error) {
+		reply, err := next(ctx, call)
+		if call.Method == "GET" {
+			select {
+			case polls <- struct{}{}:
+			default:
+			}
+		}
+		return reply, err
+	})
+
+	events, errs := group.WatchMembers(ctx, &WatchMembersOptions{Interval: 20 * time.Millisecond, Jitter: 5 * time.Millisecond})
+	<-polls
+
+	if err := group.Invite(ctx, "carol"); err != nil {
+		t.Fatalf("Invite failed: %v", err)
+	}
+	if err := group.SetMemberRole(ctx, "bob", Manager); err != nil {
+		t.Fatalf("SetMemberRole failed: %v", err)
+	}
+
+	got := map[MemberEventType]string{}
+	for len(got) < 2 {
+		select {
+		case event := <-events:
+			got[event.Type] = event.Member.Name
+		case err := <-errs:
+			t.Fatalf("Watch failed: %v", err)
+		case <-ctx.Done():
+			t.Fatalf("Timed out waiting for events, got %v", got)
+		}
+	}
+	if got[MemberJoined] != "carol" || got[MemberRoleChanged] != "bob" {
+		t.Errorf("Unexpected events: %v", got)
+	}
+
+	cancel()
+	for range events {
+	}
+}
+
+// TestGroupWatchMembersZeroInterval tests that a zero interval does not poll in a tight loop
+func TestGroupWatchMembersZeroInterval(t *testing.T) {
+	fs := newFakeServer(t)
+	owner := fs.login(t, "alice")
+	group := fs.createGroup(t, owner, "Team")
+
+	var polls atomic.Int32
+	owner.client.Use(func(ctx context.Context, call *Call, next Invoker) (*Reply, error) {
+		polls.Add(1)
+		return next(ctx, call)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	events, _ := group.WatchMembers(ctx, &WatchMembersOptions{})
+	for range events {
+	}
+	if n := polls.Load(); n != 1 {
+		t.Errorf("Expected a single poll within the default interval, got %d", n)
+	}
+}
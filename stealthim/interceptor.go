@@ -0,0 +1,118 @@
+package stealthim
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// Call describes an outgoing API call as seen by interceptors
+type Call struct {
+	Method   string      // HTTP method, or "WS" for WebSocket file uploads
+	Endpoint string      // API path relative to the base URL, including the query string
+	Body     any         // Decoded request body; SDK methods use map[string]any, uploads use *FileMetadata
+	Header   http.Header // Extra headers sent with the request
+	Stream   bool        // True for streaming calls whose response body is not buffered
+}
+
+// Reply is the outcome of a call as seen by interceptors
+type Reply struct {
+	Result   Result         // Result decoded from the response, zero if the response has none
+	Response *http.Response // Raw response, nil for short-circuited calls
+	Body     []byte         // Buffered response body, nil for streaming calls
+}
+
+// Invoker performs a call, either by sending it or by passing it to the next interceptor
+type Invoker func(ctx context.Context, call *Call) (*Reply, error)
+
+// Interceptor observes or changes a call. It may modify the call before invoking next,
+// inspect or replace the reply afterwards, invoke next several times to retry,
+// or return a reply without invoking next to short-circuit the request.
+type Interceptor func(ctx context.Context, call *Call, next Invoker) (*Reply, error)
+
+// Errors returned when interceptors break the reply contract
+var (
+	errNoReply  = errors.New("interceptor returned no reply")
+	errNoStream = errors.New("streaming call returned no response")
+)
+
+// Use appends interceptors to the client's chain. The first interceptor added is the outermost.
+func (c *Client) Use(interceptors ...Interceptor) {
+	c.Interceptors = append(c.Interceptors, interceptors...)
+}
+
+// invoke runs call through the interceptor chain, ending with final
+func (c *Client) invoke(ctx context.Context, call *Call, final Invoker) (*Reply, error) {
+	next := c.withFailover(final)
+	for i := len(c.Interceptors) - 1; i >= 0; i-- {
+		interceptor, inner := c.Interceptors[i], next
+		next = func(ctx context.Context, call *Call) (*Reply, error) {
+			return interceptor(ctx, call, inner)
+		}
+	}
+
+	reply, err := next(ctx, call)
+	if err != nil {
+		return nil, err
+	}
+	if reply == nil {
+		return nil, errNoReply
+	}
+	return reply, nil
+}
+
+// response returns an HTTP response whose body holds the buffered reply body.
+// Short-circuited replies without a body are encoded as a result-only JSON response,
+// and a Result changed by an interceptor is written back into the body.
+func (r *Reply) response() *http.Response {
+	body := r.Body
+	if body == nil {
+		body, _ = json.Marshal(struct {
+			Result Result `json:"result"`
+		}{r.Result})
+	} else {
+		body = r.syncResult(body)
+	}
+
+	resp := r.Response
+	if resp == nil {
+		resp = &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     make(http.Header),
+		}
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp
+}
+
+// syncResult returns body with its result replaced by r.Result if the two differ.
+// Other fields are kept; bodies that are not JSON objects are returned unchanged.
+func (r *Reply) syncResult(body []byte) []byte {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil || fields == nil {
+		return body
+	}
+
+	var current Result
+	raw, ok := fields["result"]
+	if ok {
+		_ = json.Unmarshal(raw, &current)
+	}
+	if current == r.Result && (ok || r.Result == Result{}) {
+		return body
+	}
+
+	encoded, err := json.Marshal(r.Result)
+	if err != nil {
+		return body
+	}
+	fields["result"] = encoded
+	updated, err := json.Marshal(fields)
+	if err != nil {
+		return body
+	}
+	return updated
+}
@@ -0,0 +1,158 @@
+package stealthim
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestInterceptorHeaderAndBody tests that interceptors can change headers and the request body
+func TestInterceptorHeaderAndBody(t *testing.T) {
+	var auditHeader, nickname string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auditHeader = r.Header.Get("X-Audit")
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		nickname, _ = body["nickname"].(string)
+		w.Write([]byte(`{"result":{"code":800,"msg":""}}`))
+	}))
+	defer ts.Close()
+
+	client := NewClientWithSession(ts.URL, "test-session")
+	var seen Result
+	client.Use(func(ctx context.Context, call *Call, next Invoker) (*Reply, error) {
+		call.Header.Set("X-Audit", "tester")
+		if body, ok := call.Body.(map[string]any); ok {
+			body["nickname"] = "changed"
+		}
+		reply, err := next(ctx, call)
+		if err == nil {
+			seen = reply.Result
+		}
+		return reply, err
+	})
+
+	user := &User{client: client}
+	if err := user.ChangeNickname(context.Background(), "original"); err != nil {
+		t.Fatalf("ChangeNickname failed: %v", err)
+	}
+
+	if auditHeader != "tester" {
+		t.Errorf("Expected audit header, got %q", auditHeader)
+	}
+	if nickname != "changed" {
+		t.Errorf("Expected modified body, got %q", nickname)
+	}
+	if !seen.IsSuccess() {
+		t.Errorf("Expected interceptor to see a successful result, got %+v", seen)
+	}
+}
+
+// TestInterceptorShortCircuit tests that interceptors can answer without sending the request
+func TestInterceptorShortCircuit(t *testing.T) {
+	client := NewClient("http://127.0.0.1:0")
+	client.Use(func(ctx context.Context, call *Call, next Invoker) (*Reply, error) {
+		return &Reply{Result: Result{Code: 1302, Msg: "injected"}}, nil
+	})
+
+	group := &Group{client: client, GroupID: 1}
+	err := group.ChangeName(context.Background(), "name")
+	var stealthErr *StealthError
+	if !errors.As(err, &stealthErr) || stealthErr.Code != 1302 {
+		t.Errorf("Expected injected StealthError, got %v", err)
+	}
+}
+
+// TestInterceptorChangeResult tests that a result changed on a real reply reaches the caller
+func TestInterceptorChangeResult(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v1/group" {
+			w.Write([]byte(`{"result":{"code":1302,"msg":"denied"},"groups":[4,5]}`))
+			return
+		}
+		w.Write([]byte(`{"result":{"code":800,"msg":""}}`))
+	}))
+	defer ts.Close()
+
+	client := NewClientWithSession(ts.URL, "test-session")
+	client.Use(func(ctx context.Context, call *Call, next Invoker) (*Reply, error) {
+		reply, err := next(ctx, call)
+		if err != nil {
+			return nil, err
+		}
+		if reply.Result.IsSuccess() {
+			reply.Result = Result{Code: fakeCodePermissionDenied, Msg: "blocked by policy"}
+		} else {
+			reply.Result = Result{Code: CodeSuccess}
+		}
+		return reply, nil
+	})
+
+	user := &User{client: client}
+	if err := user.ChangeNickname(context.Background(), "nick"); resultCode(err) != fakeCodePermissionDenied {
+		t.Errorf("Expected the interceptor's failure, got %v", err)
+	}
+	groups, err := user.GetGroups(context.Background())
+	if err != nil || len(groups) != 2 || groups[0] != 4 {
+		t.Errorf("Expected the interceptor's success with the original fields, got %v, %v", groups, err)
+	}
+}
+
+// TestInterceptorRetry tests that interceptors can retry a call
+func TestInterceptorRetry(t *testing.T) {
+	attempts := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts < 3 {
+			w.Write([]byte(`{"result":{"code":900,"msg":"busy"}}`))
+			return
+		}
+		w.Write([]byte(`{"result":{"code":800,"msg":""},"groups":[42]}`))
+	}))
+	defer ts.Close()
+
+	client := NewClientWithSession(ts.URL, "test-session")
+	client.Use(func(ctx context.Context, call *Call, next Invoker) (*Reply, error) {
+		for {
+			reply, err := next(ctx, call)
+			if err != nil || reply.Result.IsSuccess() || reply.Result.Code != 900 {
+				return reply, err
+			}
+		}
+	})
+
+	user := &User{client: client}
+	groups, err := user.GetGroups(context.Background())
+	if err != nil {
+		t.Fatalf("GetGroups failed: %v", err)
+	}
+	if attempts != 3 || len(groups) != 1 || groups[0] != 42 {
+		t.Errorf("Unexpected result after retries: attempts=%d groups=%v", attempts, groups)
+	}
+}
+
+// TestInterceptorOrder tests that the first interceptor added is the outermost
+func TestInterceptorOrder(t *testing.T) {
+	var order []string
+	client := NewClient("http://127.0.0.1:0")
+	client.Use(
+		func(ctx context.Context, call *Call, next Invoker) (*Reply, error) {
+			order = append(order, "outer")
+			return next(ctx, call)
+		},
+		func(ctx context.Context, call *Call, next Invoker) (*Reply, error) {
+			order = append(order, "inner")
+			return &Reply{Result: Result{Code: 800}}, nil
+		},
+	)
+
+	if _, err := client.GetFileInfo(context.Background(), "hash"); err != nil {
+		t.Fatalf("GetFileInfo failed: %v", err)
+	}
+	if len(order) != 2 || order[0] != "outer" || order[1] != "inner" {
+		t.Errorf("Unexpected interceptor order: %v", order)
+	}
+}
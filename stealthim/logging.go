@@ -0,0 +1,90 @@
+package stealthim
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/url"
+	"strings"
+)
+
+// redactedValue replaces sensitive values in log output
+const redactedValue = "[REDACTED]"
+
+// sensitiveKeys lists request fields and query parameters that must never be logged
+var sensitiveKeys = map[string]bool{
+	"password":      true,
+	"session":       true,
+	"authorization": true,
+}
+
+// discardHandler is a slog.Handler that drops every record
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
+
+// discardLogger is used when no logger has been configured on the client
+var discardLogger = slog.New(discardHandler{})
+
+// logger returns the configured logger or a logger that discards everything
+func (c *Client) logger() *slog.Logger {
+	if c.Logger != nil {
+		return c.Logger
+	}
+	return discardLogger
+}
+
+// redactedBody wraps a request body so that it is logged with sensitive fields hidden
+type redactedBody struct {
+	body any
+}
+
+// LogValue implements slog.LogValuer
+func (r redactedBody) LogValue() slog.Value {
+	if r.body == nil {
+		return slog.StringValue("")
+	}
+
+	// Round-trip through JSON so that structs and maps are handled the same way
+	data, err := json.Marshal(r.body)
+	if err != nil {
+		return slog.StringValue("<unserializable>")
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return slog.StringValue(string(data))
+	}
+
+	attrs := make([]slog.Attr, 0, len(fields))
+	for key, value := range fields {
+		if sensitiveKeys[strings.ToLower(key)] {
+			value = redactedValue
+		}
+		attrs = append(attrs, slog.Any(key, value))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// redactURL hides sensitive query parameters such as the WebSocket authorization token
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	q := u.Query()
+	changed := false
+	for key := range q {
+		if sensitiveKeys[strings.ToLower(key)] {
+			q.Set(key, redactedValue)
+			changed = true
+		}
+	}
+	if !changed {
+		return rawURL
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
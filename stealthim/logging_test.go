@@ -0,0 +1,89 @@
+package stealthim
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestClientLoggerRedaction tests that request logs hide passwords and sessions
+func TestClientLoggerRedaction(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"result":{"code":800,"msg":""},"session":"secret-session","user_info":{"username":"alice"}}`))
+	}))
+	defer ts.Close()
+
+	var buf bytes.Buffer
+	client := NewClient(ts.URL)
+	client.Logger = slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	server := NewServerWithClient(client)
+	if _, _, err := server.Login(context.Background(), "alice", "Sup3rSecret"); err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+
+	output := buf.String()
+	if strings.Contains(output, "Sup3rSecret") {
+		t.Errorf("Password leaked into log output: %s", output)
+	}
+	if !strings.Contains(output, `"endpoint":"/api/v1/user"`) {
+		t.Errorf("Expected endpoint in log output: %s", output)
+	}
+	if !strings.Contains(output, `"code":800`) {
+		t.Errorf("Expected result code in log output: %s", output)
+	}
+	if !strings.Contains(output, "latency") {
+		t.Errorf("Expected latency in log output: %s", output)
+	}
+}
+
+// TestClientLoggerFailedResult tests that failed results are logged as warnings
+func TestClientLoggerFailedResult(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"result":{"code":%d,"msg":"user not found"}}`, fakeCodeUserNotFound)
+	}))
+	defer ts.Close()
+
+	var buf bytes.Buffer
+	client := NewClientWithSession(ts.URL, "secret-session")
+	client.Logger = slog.New(slog.NewJSONHandler(&buf, nil))
+
+	user := &User{client: client}
+	if _, err := user.GetUserInfo(context.Background(), "bob"); err == nil {
+		t.Fatal("Expected error for failed result")
+	}
+
+	output := buf.String()
+	if !strings.Contains(output, `"level":"WARN"`) || !strings.Contains(output, fmt.Sprintf(`"code":%d`, fakeCodeUserNotFound)) {
+		t.Errorf("Expected warning with result code, got: %s", output)
+	}
+	if strings.Contains(output, "secret-session") {
+		t.Errorf("Session leaked into log output: %s", output)
+	}
+}
+
+// TestRedactURL tests the redactURL function
+func TestRedactURL(t *testing.T) {
+	redacted := redactURL("wss://example.com/api/v1/file/?authorization=secret-session")
+	if strings.Contains(redacted, "secret-session") {
+		t.Errorf("Expected session to be redacted, got %s", redacted)
+	}
+
+	plain := "wss://example.com/api/v1/file/"
+	if redactURL(plain) != plain {
+		t.Errorf("Expected URL without sensitive parameters to be unchanged")
+	}
+}
+
+// TestClientNilLogger tests that a client without a logger still works
+func TestClientNilLogger(t *testing.T) {
+	client := NewClient("https://example.com")
+	if client.logger() == nil {
+		t.Error("Expected a non-nil fallback logger")
+	}
+}
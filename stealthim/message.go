@@ -5,37 +5,105 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"strings"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
-// SendMessage sends a text message to the group
-func (g *Group) SendMessage(ctx context.Context, msgType MessageType, content string) error {
+// SentMessage is the receipt of a message accepted by the server
+type SentMessage struct {
+	GroupID  int64
+	MsgID    string    // Server-assigned ID, empty if the server does not report it
+	Time     Timestamp // Server time, zero if the server does not report it
+	Type     MessageType
+	Msg      string // Content as sent to the server
+	Username string // Sender, empty if it could not be resolved
+	ClientID string // ID sent along with the message to find its echo
+}
+
+// Matches reports whether msg, received through ReceiveMessages, is the echo of this message.
+// Servers that report neither the message ID nor the client ID cannot be correlated, Matches
+// then always returns false.
+func (s *SentMessage) Matches(msg Message) bool {
+	if s.MsgID != "" && msg.MsgID != "" {
+		return s.MsgID == msg.MsgID
+	}
+	return s.ClientID != "" && msg.ClientID == s.ClientID
+}
+
+// Resolve fills in the message ID and time from msg if it is the echo of this message, for
+// servers that leave them out of the send reply. It reports whether msg matched.
+func (s *SentMessage) Resolve(msg Message) bool {
+	if !s.Matches(msg) {
+		return false
+	}
+	if s.MsgID == "" {
+		s.MsgID = msg.MsgID
+	}
+	if s.Time.IsZero() {
+		s.Time = msg.Time
+	}
+	return true
+}
+
+// SendMessage sends a message to the group and returns its receipt
+func (g *Group) SendMessage(ctx context.Context, msgType MessageType, content string) (*SentMessage, error) {
+	clientID, err := GenerateRandomString(16)
+	if err != nil {
+		return nil, err
+	}
+	return g.sendMessage(ctx, msgType, content, clientID)
+}
+
+// sendMessage sends a message with a client ID. Servers that support client IDs store
+// a message only once per ID, so a send can be repeated after a lost reply.
+func (g *Group) sendMessage(ctx context.Context, msgType MessageType, content, clientID string) (*SentMessage, error) {
+	// Reject message types the server is known not to support
+	if info := g.client.cachedServerInfo(); info != nil && !info.Features.SupportsMessageType(msgType) {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedMessageType, msgType)
+	}
+
 	reqBody := map[string]any{
-		"type": int(msgType),
-		"msg":  content,
+		"type":      int(msgType),
+		"msg":       content,
+		"client_id": clientID,
 	}
 
 	endpoint := fmt.Sprintf("/api/v1/message/%d", g.GroupID)
 	resp, err := g.client.doRequest(ctx, "POST", endpoint, reqBody)
 	if err != nil {
-		return fmt.Errorf("send message request failed: %w", err)
+		return nil, fmt.Errorf("send message request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	var response struct {
-		Result Result `json:"result"`
+		Result Result    `json:"result"`
+		MsgID  string    `json:"msgid"`
+		Time   Timestamp `json:"time"`
 	}
 	if err := g.client.parseResponse(resp, &response); err != nil {
-		return fmt.Errorf("failed to parse send message response: %w", err)
+		return nil, fmt.Errorf("failed to parse send message response: %w", err)
 	}
 
 	if !response.Result.IsSuccess() {
-		return response.Result.ToError()
+		return nil, response.Result.ToError()
 	}
 
-	return nil
+	// The message was sent, so a failed lookup only leaves the sender empty
+	username, _ := g.client.selfUsername(ctx)
+	return &SentMessage{
+		GroupID:  g.GroupID,
+		MsgID:    response.MsgID,
+		Time:     response.Time,
+		Type:     msgType,
+		Msg:      content,
+		Username: username,
+		ClientID: clientID,
+	}, nil
 }
 
 // RecallMessage recalls/deletes a message
@@ -95,6 +163,19 @@ func (g *Group) ReceiveMessages(ctx context.Context, opts *ReceiveMessageOptions
 		defer close(messageChan)
 		defer close(errorChan)
 
+		logger := g.client.logger()
+		tel := g.client.telemetry()
+		tel.subscriptions.Add(ctx, 1)
+		defer tel.subscriptions.Add(ctx, -1)
+
+		// Each connection attempt gets its own span
+		var span trace.Span
+		defer func() {
+			if span != nil {
+				span.End()
+			}
+		}()
+
 		// Retry logic: attempt up to 3 times
 		maxRetries := 3
 		for attempt := 0; attempt < maxRetries; attempt++ {
@@ -106,26 +187,42 @@ func (g *Group) ReceiveMessages(ctx context.Context, opts *ReceiveMessageOptions
 			default:
 			}
 
-			// Create HTTP request
-			req, err := http.NewRequestWithContext(ctx, "GET", g.client.BaseURL+endpoint, nil)
-			if err != nil {
-				errorChan <- fmt.Errorf("failed to create request: %w", err)
-				return
+			if span != nil {
+				span.End()
 			}
-
-			// Set authorization header
-			if g.client.Session != "" {
-				req.Header.Set("Authorization", "Bearer "+g.client.Session)
+			var connCtx context.Context
+			connCtx, span = tel.startSpan(ctx, "StealthIM ReceiveMessages",
+				attribute.Int64("stealthim.group.id", g.GroupID),
+				attribute.Int("stealthim.attempt", attempt+1),
+			)
+
+			// Describe the SSE request
+			call := &Call{
+				Method:   "GET",
+				Endpoint: endpoint,
+				Header:   make(http.Header),
+				Stream:   true,
 			}
-
-			// Set Accept header for SSE
-			req.Header.Set("Accept", "text/event-stream")
-			req.Header.Set("Cache-Control", "no-cache")
-			req.Header.Set("Connection", "keep-alive")
+			call.Header.Set("Accept", "text/event-stream")
+			call.Header.Set("Cache-Control", "no-cache")
+			call.Header.Set("Connection", "keep-alive")
 
 			// Execute request
-			resp, err := g.client.HTTPClient.Do(req)
+			var resp *http.Response
+			reply, err := g.client.invoke(connCtx, call, g.client.stream)
+			if err == nil {
+				if resp = reply.Response; resp == nil {
+					err = errNoStream
+				}
+			}
 			if err != nil {
+				tel.recordError(ctx, span, err, 0)
+				logger.LogAttrs(ctx, slog.LevelWarn, "sse connection failed",
+					slog.Int64("group_id", g.GroupID),
+					slog.Int("attempt", attempt+1),
+					slog.Int("max_attempts", maxRetries),
+					slog.Any("error", err),
+				)
 				// If this was the last attempt, return the error
 				if attempt == maxRetries-1 {
 					errorChan <- fmt.Errorf("failed to execute request after %d attempts: %w", maxRetries, err)
@@ -138,6 +235,13 @@ func (g *Group) ReceiveMessages(ctx context.Context, opts *ReceiveMessageOptions
 			// Check response status
 			if resp.StatusCode != http.StatusOK {
 				resp.Body.Close()
+				tel.recordError(ctx, span, fmt.Errorf("request failed with status: %d", resp.StatusCode), 0)
+				logger.LogAttrs(ctx, slog.LevelWarn, "sse connection rejected",
+					slog.Int64("group_id", g.GroupID),
+					slog.Int("attempt", attempt+1),
+					slog.Int("max_attempts", maxRetries),
+					slog.Int("status", resp.StatusCode),
+				)
 				// If this was the last attempt, return the error
 				if attempt == maxRetries-1 {
 					errorChan <- fmt.Errorf("request failed with status: %d after %d attempts", resp.StatusCode, maxRetries)
@@ -147,6 +251,11 @@ func (g *Group) ReceiveMessages(ctx context.Context, opts *ReceiveMessageOptions
 				continue
 			}
 
+			logger.LogAttrs(ctx, slog.LevelDebug, "sse connected",
+				slog.Int64("group_id", g.GroupID),
+				slog.Int("attempt", attempt+1),
+			)
+
 			// Create a buffered reader for the response body
 			reader := bufio.NewReader(resp.Body)
 
@@ -211,6 +320,7 @@ func (g *Group) ReceiveMessages(ctx context.Context, opts *ReceiveMessageOptions
 					// Check if the response is successful
 					if !response.Result.IsSuccess() {
 						resp.Body.Close()
+						tel.recordError(ctx, span, response.Result.ToError(), response.Result.Code)
 						errorChan <- response.Result.ToError()
 						return
 					}
@@ -231,6 +341,11 @@ func (g *Group) ReceiveMessages(ctx context.Context, opts *ReceiveMessageOptions
 			// If we reach this point, we had a connection issue and need to retry
 			// Wait briefly before retrying (except on the last attempt)
 			if attempt < maxRetries-1 {
+				logger.LogAttrs(ctx, slog.LevelInfo, "sse reconnecting",
+					slog.Int64("group_id", g.GroupID),
+					slog.Int("attempt", attempt+2),
+					slog.Int("max_attempts", maxRetries),
+				)
 				select {
 				case <-time.After(1 * time.Second): // Wait 1 second before retry
 				case <-ctx.Done():
@@ -245,7 +360,7 @@ func (g *Group) ReceiveMessages(ctx context.Context, opts *ReceiveMessageOptions
 	return messageChan, errorChan
 }
 
-// SendText sends a text message to the group
-func (g *Group) SendText(ctx context.Context, message string) error {
+// SendText sends a text message to the group and returns its receipt
+func (g *Group) SendText(ctx context.Context, message string) (*SentMessage, error) {
 	return g.SendMessage(ctx, Text, message)
 }
@@ -39,7 +39,7 @@ func TestSendMessage(t *testing.T) {
 	}
 
 	// Test sending a message
-	err = newGroup.SendMessage(ctx, Text, "Hello, World!")
+	_, err = newGroup.SendMessage(ctx, Text, "Hello, World!")
 	if err != nil {
 		t.Errorf("Failed to send message: %v", err)
 	}
@@ -77,7 +77,7 @@ func TestRecallMessage(t *testing.T) {
 	}
 
 	// Send a message first
-	err = newGroup.SendMessage(ctx, Text, "Hello, World!")
+	_, err = newGroup.SendMessage(ctx, Text, "Hello, World!")
 	if err != nil {
 		t.Fatalf("Failed to send message: %v", err)
 	}
@@ -120,7 +120,7 @@ func TestSendText(t *testing.T) {
 	}
 
 	// Test sending a text message
-	err = newGroup.SendText(ctx, "Hello, World!")
+	_, err = newGroup.SendText(ctx, "Hello, World!")
 	if err != nil {
 		t.Errorf("Failed to send text: %v", err)
 	}
@@ -188,3 +188,76 @@ func TestDefaultReceiveMessageOptions(t *testing.T) {
 		t.Error("Expected MsgID to be empty")
 	}
 }
+
+// TestSendMessageReceipt tests that SendMessage reports the message ID and time assigned by the server
+func TestSendMessageReceipt(t *testing.T) {
+	fs := newFakeServer(t)
+	alice := fs.login(t, "alice")
+	group := fs.createGroup(t, alice, "Team")
+	ctx := context.Background()
+
+	sent, err := group.SendText(ctx, "hello")
+	if err != nil {
+		t.Fatalf("SendText failed: %v", err)
+	}
+	if sent.MsgID == "" || sent.GroupID != group.GroupID || sent.Type != Text || sent.Msg != "hello" || sent.Username != "alice" {
+		t.Errorf("Unexpected receipt: %+v", sent)
+	}
+	if time.Since(sent.Time.Time).Abs() > time.Minute {
+		t.Errorf("Expected the server time, got %v", sent.Time)
+	}
+
+	// Older servers do not report the ID, the echo is then found by the client ID
+	fs.mu.Lock()
+	fs.noReceipts = true
+	fs.mu.Unlock()
+	old, err := group.SendText(ctx, "hello")
+	if err != nil {
+		t.Fatalf("SendText failed: %v", err)
+	}
+	if old.MsgID != "" || !old.Time.IsZero() {
+		t.Errorf("Expected an empty receipt, got %+v", old)
+	}
+
+	received := receiveRaw(t, group, 2)
+	if !sent.Matches(received[0]) || sent.Matches(received[1]) {
+		t.Errorf("Expected the receipt to match only its own echo: %+v", received)
+	}
+	// Both messages have the same content, only the second one is the echo
+	if old.Matches(received[0]) || old.Resolve(received[0]) || old.MsgID != "" {
+		t.Errorf("Expected the receipt not to match an identical message: %+v", received[0])
+	}
+	if !old.Resolve(received[1]) || old.MsgID != received[1].MsgID || old.Time != received[1].Time {
+		t.Errorf("Expected the receipt to be resolved from its echo, got %+v", old)
+	}
+
+	// Without client IDs the echo cannot be told apart from other messages
+	fs.mu.Lock()
+	fs.noClientIDs = true
+	fs.mu.Unlock()
+	oldest, err := group.SendText(ctx, "hello")
+	if err != nil {
+		t.Fatalf("SendText failed: %v", err)
+	}
+	for _, msg := range receiveRaw(t, group, 3) {
+		if oldest.Matches(msg) {
+			t.Errorf("Expected no match without message or client IDs, got %+v", msg)
+		}
+	}
+}
+
+// TestSendMessageReceiptRestoredSession tests the sender of a receipt for a client restored from a session
+func TestSendMessageReceiptRestoredSession(t *testing.T) {
+	fs := newFakeServer(t)
+	alice := fs.login(t, "alice")
+	group := fs.createGroup(t, alice, "Team")
+	restored := &Group{client: NewClientWithSession(fs.URL, alice.client.Session), GroupID: group.GroupID}
+
+	sent, err := restored.SendText(context.Background(), "hello")
+	if err != nil {
+		t.Fatalf("SendText failed: %v", err)
+	}
+	if sent.Username != "alice" {
+		t.Errorf("Expected the sender alice, got %q", sent.Username)
+	}
+}
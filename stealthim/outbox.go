@@ -0,0 +1,400 @@
+package stealthim
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrOutboxEntryNotFound is returned for unknown outbox entry IDs
+var ErrOutboxEntryNotFound = errors.New("outbox entry not found")
+
+// OutboxState is the delivery state of a queued message
+type OutboxState int
+
+// Outbox states
+const (
+	OutboxQueued OutboxState = iota // Waiting to be sent, possibly after a failed attempt
+	OutboxSent                      // Accepted by the server
+	OutboxFailed                    // Out of attempts or not sendable at all, see Retry
+)
+
+// String returns the name of the state
+func (s OutboxState) String() string {
+	switch s {
+	case OutboxQueued:
+		return "queued"
+	case OutboxSent:
+		return "sent"
+	case OutboxFailed:
+		return "failed"
+	default:
+		return fmt.Sprintf("OutboxState(%d)", int(s))
+	}
+}
+
+// OutboxEntry is a message in the outbox
+type OutboxEntry struct {
+	ID          string      `json:"id"`              // Client-generated ID, used to de-duplicate
+	MsgID       string      `json:"msgid,omitempty"` // Server-assigned ID once sent
+	GroupID     int64       `json:"group_id"`
+	Type        MessageType `json:"type"`
+	Msg         string      `json:"msg"`
+	State       OutboxState `json:"state"`
+	Attempts    int         `json:"attempts"`
+	LastError   string      `json:"last_error,omitempty"`
+	CreateTime  time.Time   `json:"create_time"`
+	NextAttempt time.Time   `json:"next_attempt"` // Earliest time of the next attempt while queued
+}
+
+// OutboxOptions holds options for an Outbox
+type OutboxOptions struct {
+	MaxAttempts int           // Attempts before a message is marked failed, 0 to retry until it is sent
+	MinBackoff  time.Duration // Delay after the first failed attempt, doubled after each further failure
+	MaxBackoff  time.Duration // Upper limit of the delay between attempts
+}
+
+// DefaultOutboxOptions returns default options for an outbox
+func DefaultOutboxOptions() *OutboxOptions {
+	return &OutboxOptions{
+		MaxAttempts: 10,
+		MinBackoff:  time.Second,
+		MaxBackoff:  5 * time.Minute,
+	}
+}
+
+// OutboxStateFunc is called with a copy of an entry whenever its state changes
+type OutboxStateFunc func(entry OutboxEntry)
+
+// Outbox queues outgoing messages in a file, so they survive network outages and restarts,
+// and delivers them with Run.
+//
+// Messages of one group are sent in the order they were enqueued: a message is only sent once
+// the previous one of its group has been sent or has failed. Network errors and rejections by
+// the server are retried with exponential backoff until MaxAttempts is reached; messages the
+// SDK knows the server cannot accept, such as unsupported types, fail right away.
+//
+// Every attempt carries the entry ID as client ID, so a message that is sent again after the
+// reply was lost is stored only once. Servers without client ID support may store it twice,
+// delivery is at least once.
+type Outbox struct {
+	user *User
+	path string
+	opts OutboxOptions
+	now  func() time.Time
+
+	mu           sync.Mutex
+	entries      []*OutboxEntry // In enqueue order
+	wake         chan struct{}
+	listeners    map[int]OutboxStateFunc
+	nextListener int
+}
+
+// OpenOutbox opens the outbox stored at path, creating it on first use, and sends
+// its messages on behalf of user
+func OpenOutbox(user *User, path string, opts *OutboxOptions) (*Outbox, error) {
+	if opts == nil {
+		opts = DefaultOutboxOptions()
+	}
+	o := &Outbox{
+		user:      user,
+		path:      path,
+		opts:      *opts,
+		now:       time.Now,
+		wake:      make(chan struct{}, 1),
+		listeners: make(map[int]OutboxStateFunc),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read outbox: %w", err)
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &o.entries); err != nil {
+			return nil, fmt.Errorf("failed to parse outbox: %w", err)
+		}
+	}
+	return o, nil
+}
+
+// Enqueue stores a message for delivery and returns its entry. If id is empty a random ID is
+// generated. Enqueueing an ID that is already in the outbox returns the existing entry without
+// queueing the message again, so callers can safely repeat Enqueue after a crash.
+func (o *Outbox) Enqueue(id string, groupID int64, msgType MessageType, content string) (OutboxEntry, error) {
+	if id == "" {
+		var err error
+		if id, err = GenerateRandomString(32); err != nil {
+			return OutboxEntry{}, err
+		}
+	}
+
+	o.mu.Lock()
+	if existing := o.find(id); existing != nil {
+		entry := *existing
+		o.mu.Unlock()
+		return entry, nil
+	}
+	entry := &OutboxEntry{
+		ID:          id,
+		GroupID:     groupID,
+		Type:        msgType,
+		Msg:         content,
+		State:       OutboxQueued,
+		CreateTime:  o.now(),
+		NextAttempt: o.now(),
+	}
+	o.entries = append(o.entries, entry)
+	if err := o.save(); err != nil {
+		o.entries = o.entries[:len(o.entries)-1]
+		o.mu.Unlock()
+		return OutboxEntry{}, err
+	}
+	snapshot := *entry
+	o.mu.Unlock()
+
+	o.notify(snapshot)
+	o.signal()
+	return snapshot, nil
+}
+
+// Get returns the entry with the given ID
+func (o *Outbox) Get(id string) (OutboxEntry, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if entry := o.find(id); entry != nil {
+		return *entry, true
+	}
+	return OutboxEntry{}, false
+}
+
+// Entries returns all entries in enqueue order
+func (o *Outbox) Entries() []OutboxEntry {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	entries := make([]OutboxEntry, len(o.entries))
+	for i, entry := range o.entries {
+		entries[i] = *entry
+	}
+	return entries
+}
+
+// Retry queues a failed message again with a fresh attempt count
+func (o *Outbox) Retry(id string) error {
+	o.mu.Lock()
+	entry := o.find(id)
+	if entry == nil {
+		o.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrOutboxEntryNotFound, id)
+	}
+	if entry.State != OutboxFailed {
+		o.mu.Unlock()
+		return nil
+	}
+	entry.State, entry.Attempts, entry.NextAttempt = OutboxQueued, 0, o.now()
+	snapshot := *entry
+	err := o.save()
+	o.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	o.notify(snapshot)
+	o.signal()
+	return nil
+}
+
+// Prune removes sent messages from the outbox. Their IDs are no longer de-duplicated afterwards.
+func (o *Outbox) Prune() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	kept := o.entries[:0:0]
+	for _, entry := range o.entries {
+		if entry.State != OutboxSent {
+			kept = append(kept, entry)
+		}
+	}
+	o.entries = kept
+	return o.save()
+}
+
+// OnStateChange registers fn to be called when an entry is enqueued or changes state.
+// It returns a function that removes the listener.
+func (o *Outbox) OnStateChange(fn OutboxStateFunc) (remove func()) {
+	o.mu.Lock()
+	id := o.nextListener
+	o.nextListener++
+	o.listeners[id] = fn
+	o.mu.Unlock()
+
+	return func() {
+		o.mu.Lock()
+		delete(o.listeners, id)
+		o.mu.Unlock()
+	}
+}
+
+// Run delivers queued messages until ctx is cancelled. Only one Run should be active per outbox.
+func (o *Outbox) Run(ctx context.Context) error {
+	for {
+		ready, wait := o.due()
+		for _, entry := range ready {
+			o.deliver(ctx, entry)
+		}
+		if len(ready) > 0 && ctx.Err() == nil {
+			continue
+		}
+
+		var timer *time.Timer
+		var fire <-chan time.Time
+		if wait >= 0 {
+			timer = time.NewTimer(wait)
+			fire = timer.C
+		}
+		select {
+		case <-ctx.Done():
+			if timer != nil {
+				timer.Stop()
+			}
+			return ctx.Err()
+		case <-o.wake:
+		case <-fire:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+// due returns the next message of each group that can be sent now, and how long to wait
+// for the next message whose backoff has not passed yet, or -1 if there is none
+func (o *Outbox) due() ([]OutboxEntry, time.Duration) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	now := o.now()
+	wait := time.Duration(-1)
+	seen := make(map[int64]bool)
+	var ready []OutboxEntry
+	for _, entry := range o.entries {
+		if entry.State != OutboxQueued || seen[entry.GroupID] {
+			continue
+		}
+		seen[entry.GroupID] = true
+		if delay := entry.NextAttempt.Sub(now); delay > 0 {
+			if wait < 0 || delay < wait {
+				wait = delay
+			}
+			continue
+		}
+		ready = append(ready, *entry)
+	}
+	return ready, wait
+}
+
+// deliver makes one attempt to send a message and records the outcome
+func (o *Outbox) deliver(ctx context.Context, entry OutboxEntry) {
+	sent, err := o.user.OpenGroup(entry.GroupID).sendMessage(ctx, entry.Type, entry.Msg, entry.ID)
+	if err != nil && ctx.Err() != nil {
+		return // Shutting down, the attempt does not count
+	}
+
+	o.mu.Lock()
+	current := o.find(entry.ID)
+	if current == nil || current.State != OutboxQueued {
+		o.mu.Unlock()
+		return
+	}
+	current.Attempts++
+	switch {
+	case err == nil:
+		current.State, current.LastError, current.MsgID = OutboxSent, "", sent.MsgID
+	case isPermanentSendError(err) || (o.opts.MaxAttempts > 0 && current.Attempts >= o.opts.MaxAttempts):
+		current.State, current.LastError = OutboxFailed, err.Error()
+	default:
+		current.LastError = err.Error()
+		current.NextAttempt = o.now().Add(o.backoff(current.Attempts))
+	}
+	snapshot := *current
+	saveErr := o.save()
+	o.mu.Unlock()
+
+	if saveErr != nil {
+		o.user.client.logger().LogAttrs(ctx, slog.LevelWarn, "failed to save outbox",
+			slog.String("path", o.path),
+			slog.Any("error", saveErr),
+		)
+	}
+	o.notify(snapshot)
+}
+
+// backoff returns the delay after the given number of failed attempts
+func (o *Outbox) backoff(attempts int) time.Duration {
+	delay := o.opts.MinBackoff
+	for i := 1; i < attempts && delay < o.opts.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if o.opts.MaxBackoff > 0 && delay > o.opts.MaxBackoff {
+		delay = o.opts.MaxBackoff
+	}
+	return delay
+}
+
+// isPermanentSendError reports whether retrying a send cannot succeed. Only errors found
+// by the SDK itself qualify: the server's result codes are not documented, so a rejection
+// by the server may be temporary, such as an expired session, and is retried.
+func isPermanentSendError(err error) bool {
+	return errors.Is(err, ErrUnsupportedMessageType)
+}
+
+// find returns the entry with the given ID, o.mu must be held
+func (o *Outbox) find(id string) *OutboxEntry {
+	for _, entry := range o.entries {
+		if entry.ID == id {
+			return entry
+		}
+	}
+	return nil
+}
+
+// save writes the outbox to disk atomically, o.mu must be held
+func (o *Outbox) save() error {
+	data, err := json.Marshal(o.entries)
+	if err != nil {
+		return err
+	}
+	tmp := o.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write outbox: %w", err)
+	}
+	if err := os.Rename(tmp, o.path); err != nil {
+		return fmt.Errorf("failed to write outbox: %w", err)
+	}
+	return nil
+}
+
+// notify calls the state listeners
+func (o *Outbox) notify(entry OutboxEntry) {
+	o.mu.Lock()
+	listeners := make([]OutboxStateFunc, 0, len(o.listeners))
+	for _, fn := range o.listeners {
+		listeners = append(listeners, fn)
+	}
+	o.mu.Unlock()
+
+	for _, fn := range listeners {
+		fn(entry)
+	}
+}
+
+// signal wakes up Run
+func (o *Outbox) signal() {
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+}
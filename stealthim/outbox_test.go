@@ -0,0 +1,237 @@
+package stealthim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestOutbox tests ordered delivery with retries and permanent failures
+func TestOutbox(t *testing.T) {
+	fs := newFakeServer(t)
+	alice := fs.login(t, "alice")
+	group := fs.createGroup(t, alice, "Team")
+
+	// The network is down for the first two attempts to send to the group
+	var outages atomic.Int32
+	outages.Store(2)
+	endpoint := fmt.Sprintf("/api/v1/message/%d", group.GroupID)
+	alice.client.Use(func(ctx context.Context, call *Call, next Invoker) (*Reply, error) {
+		if call.Method == "POST" && call.Endpoint == endpoint && outages.Add(-1) >= 0 {
+			return nil, errors.New("network is unreachable")
+		}
+		return next(ctx, call)
+	})
+
+	outbox, err := OpenOutbox(alice, filepath.Join(t.TempDir(), "outbox.json"), &OutboxOptions{
+		MaxAttempts: 5,
+		MinBackoff:  5 * time.Millisecond,
+		MaxBackoff:  20 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("OpenOutbox failed: %v", err)
+	}
+	done := make(chan OutboxEntry, 10)
+	outbox.OnStateChange(func(entry OutboxEntry) {
+		if entry.State != OutboxQueued {
+			done <- entry
+		}
+	})
+
+	first, _ := outbox.Enqueue("", group.GroupID, Text, "first")
+	second, _ := outbox.Enqueue("", group.GroupID, Text, "second")
+	lost, _ := outbox.Enqueue("", group.GroupID+100, Text, "nowhere")
+	if first.ID == "" || first.ID == second.ID || first.State != OutboxQueued {
+		t.Fatalf("Unexpected entries: %+v %+v", first, second)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go outbox.Run(ctx)
+
+	states := make(map[string]OutboxEntry)
+	for len(states) < 3 {
+		select {
+		case entry := <-done:
+			states[entry.ID] = entry
+		case <-ctx.Done():
+			t.Fatalf("Timed out, got %v", states)
+		}
+	}
+
+	if entry := states[lost.ID]; entry.State != OutboxFailed || entry.Attempts != 5 || entry.LastError == "" {
+		t.Errorf("Expected the message to a missing group to fail after all attempts, got %+v", entry)
+	}
+	if entry := states[first.ID]; entry.State != OutboxSent || entry.Attempts < 2 || entry.MsgID == "" {
+		t.Errorf("Expected the first message to be sent after retries, got %+v", entry)
+	}
+	if entry := states[second.ID]; entry.State != OutboxSent {
+		t.Errorf("Expected the second message to be sent, got %+v", entry)
+	}
+
+	fs.mu.Lock()
+	messages := fs.groups[group.GroupID].messages
+	fs.mu.Unlock()
+	if len(messages) != 2 || messages[0].Msg != "first" || messages[1].Msg != "second" {
+		t.Errorf("Expected the messages in order, got %+v", messages)
+	}
+
+	if err := outbox.Retry("missing"); !errors.Is(err, ErrOutboxEntryNotFound) {
+		t.Errorf("Expected ErrOutboxEntryNotFound, got %v", err)
+	}
+	if err := outbox.Prune(); err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	if entries := outbox.Entries(); len(entries) != 1 || entries[0].ID != lost.ID {
+		t.Errorf("Expected only the failed message after Prune, got %+v", entries)
+	}
+}
+
+// TestOutboxPersistence tests that queued messages survive a restart and IDs are de-duplicated
+func TestOutboxPersistence(t *testing.T) {
+	fs := newFakeServer(t)
+	alice := fs.login(t, "alice")
+	group := fs.createGroup(t, alice, "Team")
+	path := filepath.Join(t.TempDir(), "outbox.json")
+
+	outbox, err := OpenOutbox(alice, path, nil)
+	if err != nil {
+		t.Fatalf("OpenOutbox failed: %v", err)
+	}
+	if _, err := outbox.Enqueue("msg-1", group.GroupID, Text, "hello"); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+
+	// Restart before the message was sent
+	outbox, err = OpenOutbox(alice, path, nil)
+	if err != nil {
+		t.Fatalf("OpenOutbox failed: %v", err)
+	}
+	entry, ok := outbox.Get("msg-1")
+	if !ok || entry.State != OutboxQueued || entry.Msg != "hello" {
+		t.Fatalf("Expected the queued message to be restored, got %+v", entry)
+	}
+	if again, _ := outbox.Enqueue("msg-1", group.GroupID, Text, "hello"); len(outbox.Entries()) != 1 || again.ID != "msg-1" {
+		t.Fatalf("Expected a repeated Enqueue to be ignored, got %+v", outbox.Entries())
+	}
+
+	sent := make(chan struct{})
+	outbox.OnStateChange(func(entry OutboxEntry) {
+		if entry.State == OutboxSent {
+			close(sent)
+		}
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error)
+	go func() { stopped <- outbox.Run(ctx) }()
+	select {
+	case <-sent:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for delivery")
+	}
+	cancel()
+	if err := <-stopped; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected Run to stop with context.Canceled, got %v", err)
+	}
+
+	if entry, _ := outbox.Enqueue("msg-1", group.GroupID, Text, "hello"); entry.State != OutboxSent {
+		t.Errorf("Expected the sent entry back, got %+v", entry)
+	}
+	fs.mu.Lock()
+	count := len(fs.groups[group.GroupID].messages)
+	fs.mu.Unlock()
+	if count != 1 {
+		t.Errorf("Expected the message to be sent once, got %d", count)
+	}
+}
+
+// TestOutboxLostReply tests that a message is stored once when the reply to a send was lost
+// or the server asked to retry
+func TestOutboxLostReply(t *testing.T) {
+	fs := newFakeServer(t)
+	alice := fs.login(t, "alice")
+	group := fs.createGroup(t, alice, "Team")
+
+	// The first send is accepted but its reply is lost, the second one hits an expired session
+	var attempts atomic.Int32
+	alice.client.Use(func(ctx context.Context, call *Call, next Invoker) (*Reply, error) {
+		if call.Method != "POST" || call.Endpoint != fmt.Sprintf("/api/v1/message/%d", group.GroupID) {
+			return next(ctx, call)
+		}
+		switch attempts.Add(1) {
+		case 1:
+			next(ctx, call)
+			return nil, errors.New("connection reset by peer")
+		case 2:
+			return nil, &StealthError{Code: fakeCodeUnauthorized, Msg: "session expired"}
+		}
+		return next(ctx, call)
+	})
+
+	outbox, err := OpenOutbox(alice, filepath.Join(t.TempDir(), "outbox.json"), &OutboxOptions{
+		MaxAttempts: 5,
+		MinBackoff:  5 * time.Millisecond,
+		MaxBackoff:  20 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("OpenOutbox failed: %v", err)
+	}
+	done := make(chan OutboxEntry, 1)
+	outbox.OnStateChange(func(entry OutboxEntry) {
+		if entry.State != OutboxQueued {
+			done <- entry
+		}
+	})
+	if _, err := outbox.Enqueue("msg-1", group.GroupID, Text, "hello"); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go outbox.Run(ctx)
+
+	select {
+	case entry := <-done:
+		if entry.State != OutboxSent || entry.Attempts != 3 || entry.MsgID == "" {
+			t.Errorf("Expected the message to be sent on the third attempt, got %+v", entry)
+		}
+	case <-ctx.Done():
+		t.Fatal("Timed out waiting for delivery")
+	}
+
+	fs.mu.Lock()
+	messages := fs.groups[group.GroupID].messages
+	fs.mu.Unlock()
+	if len(messages) != 1 || messages[0].ClientID != "msg-1" {
+		t.Errorf("Expected the message to be stored once, got %+v", messages)
+	}
+}
+
+// TestIsPermanentSendError tests which send errors fail a message without retrying
+func TestIsPermanentSendError(t *testing.T) {
+	for err, want := range map[error]bool{
+		&StealthError{Code: fakeCodeGroupNotFound}:                               false,
+		&StealthError{Code: fakeCodeUnauthorized}:                                false,
+		ErrUnsupportedMessageType:                                                true,
+		errors.New("network is unreachable"):                                     false,
+		fmt.Errorf("send message request failed: %w", ErrUnsupportedMessageType): true,
+	} {
+		if got := isPermanentSendError(err); got != want {
+			t.Errorf("isPermanentSendError(%v) = %v, want %v", err, got, want)
+		}
+	}
+}
+
+// TestOutboxBackoff tests the delay between attempts
+func TestOutboxBackoff(t *testing.T) {
+	outbox := &Outbox{opts: OutboxOptions{MinBackoff: time.Second, MaxBackoff: 5 * time.Second}}
+	for attempts, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 5 * time.Second, 20: 5 * time.Second} {
+		if got := outbox.backoff(attempts); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
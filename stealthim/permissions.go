@@ -0,0 +1,159 @@
+package stealthim
+
+import (
+	"context"
+	"fmt"
+)
+
+// GroupAction is an administrative operation on a group
+type GroupAction int
+
+// Group actions
+const (
+	ActionInvite GroupAction = iota
+	ActionKick
+	ActionSetRole
+	ActionChangeName
+	ActionChangePassword
+	ActionDisband
+	ActionTransferOwnership
+)
+
+// String returns the name of the action
+func (a GroupAction) String() string {
+	switch a {
+	case ActionInvite:
+		return "invite"
+	case ActionKick:
+		return "kick"
+	case ActionSetRole:
+		return "set role"
+	case ActionChangeName:
+		return "change name"
+	case ActionChangePassword:
+		return "change password"
+	case ActionDisband:
+		return "disband"
+	case ActionTransferOwnership:
+		return "transfer ownership"
+	default:
+		return fmt.Sprintf("action %d", int(a))
+	}
+}
+
+// PermissionError is returned when a group action is not allowed for the current role.
+// It matches ErrPermissionDenied with errors.Is.
+type PermissionError struct {
+	Action GroupAction
+	Target string          // Target username, empty for actions on the group itself
+	Role   GroupMemberType // Role of the current user
+	Member bool            // Whether the current user is a member of the group
+}
+
+func (e *PermissionError) Error() string {
+	if !e.Member {
+		return fmt.Sprintf("permission denied: cannot %s, not a group member", e.Action)
+	}
+	if e.Target != "" {
+		return fmt.Sprintf("permission denied: role %d cannot %s %s", e.Role, e.Action, e.Target)
+	}
+	return fmt.Sprintf("permission denied: role %d cannot %s", e.Role, e.Action)
+}
+
+// Is makes PermissionError match ErrPermissionDenied
+func (e *PermissionError) Is(target error) bool {
+	return target == ErrPermissionDenied
+}
+
+// CanPerform reports whether a member with the actor role may perform action
+// on a member with the target role. Actions on the group itself ignore target.
+//
+// Any member may invite. Managers and owners may rename the group, change its password
+// and kick members ranked below them. Only the owner may change roles, transfer
+// ownership or disband the group.
+func CanPerform(actor GroupMemberType, action GroupAction, target GroupMemberType) bool {
+	switch action {
+	case ActionInvite:
+		return true
+	case ActionKick:
+		return actor >= Manager && actor > target
+	case ActionChangeName, ActionChangePassword:
+		return actor >= Manager
+	case ActionSetRole, ActionDisband, ActionTransferOwnership:
+		return actor == Owner
+	default:
+		return false
+	}
+}
+
+// GroupPermissions answers permission questions for the current user from a member list
+type GroupPermissions struct {
+	Self     string
+	Role     GroupMemberType
+	IsMember bool
+	members  []GroupMember
+}
+
+// NewGroupPermissions creates a permission model for self from a group member list
+func NewGroupPermissions(self string, members []GroupMember) *GroupPermissions {
+	role, ok := memberRole(members, self)
+	return &GroupPermissions{
+		Self:     self,
+		Role:     role,
+		IsMember: ok,
+		members:  members,
+	}
+}
+
+// Can reports whether the current user may perform action on target.
+// Pass an empty target for actions on the group itself.
+func (p *GroupPermissions) Can(action GroupAction, target string) bool {
+	return p.Check(action, target) == nil
+}
+
+// Check returns a *PermissionError if the current user may not perform action on target
+func (p *GroupPermissions) Check(action GroupAction, target string) error {
+	denied := &PermissionError{Action: action, Target: target, Role: p.Role, Member: p.IsMember}
+	if !p.IsMember {
+		return denied
+	}
+
+	targetRole := Member
+	if target != "" {
+		role, ok := memberRole(p.members, target)
+		if !ok && action != ActionInvite {
+			return fmt.Errorf("%w: %s", ErrNotGroupMember, target)
+		}
+		targetRole = role
+	}
+
+	if !CanPerform(p.Role, action, targetRole) {
+		return denied
+	}
+	return nil
+}
+
+// Permissions returns the permission model of the current user, based on the cached member list
+func (g *Group) Permissions(ctx context.Context) (*GroupPermissions, error) {
+	self, err := g.client.selfUsername(ctx)
+	if err != nil {
+		return nil, err
+	}
+	info, err := g.Info(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return NewGroupPermissions(self, info.Members), nil
+}
+
+// checkPermission stops disallowed calls before they are sent if CheckPermissions is enabled
+func (g *Group) checkPermission(ctx context.Context, action GroupAction, target string) error {
+	if !g.CheckPermissions {
+		return nil
+	}
+	perms, err := g.Permissions(ctx)
+	if err != nil {
+		return err
+	}
+	return perms.Check(action, target)
+}
@@ -0,0 +1,131 @@
+package stealthim
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// TestCanPerform tests the role rules of CanPerform
+func TestCanPerform(t *testing.T) {
+	tests := []struct {
+		actor  GroupMemberType
+		action GroupAction
+		target GroupMemberType
+		want   bool
+	}{
+		{Member, ActionInvite, Member, true},
+		{Member, ActionKick, Member, false},
+		{Manager, ActionKick, Member, true},
+		{Manager, ActionKick, Manager, false},
+		{Owner, ActionKick, Manager, true},
+		{Member, ActionChangeName, Member, false},
+		{Manager, ActionChangePassword, Member, true},
+		{Manager, ActionSetRole, Member, false},
+		{Owner, ActionSetRole, Manager, true},
+		{Manager, ActionDisband, Member, false},
+		{Owner, ActionTransferOwnership, Member, true},
+	}
+
+	for _, tt := range tests {
+		if got := CanPerform(tt.actor, tt.action, tt.target); got != tt.want {
+			t.Errorf("CanPerform(%d, %s, %d) = %v, want %v", tt.actor, tt.action, tt.target, got, tt.want)
+		}
+	}
+}
+
+// TestGroupPermissionsCheck tests the GroupPermissions.Check method
+func TestGroupPermissionsCheck(t *testing.T) {
+	members := []GroupMember{
+		{Name: "alice", Type: int(Owner)},
+		{Name: "bob", Type: int(Manager)},
+		{Name: "carol", Type: int(Member)},
+	}
+
+	perms := NewGroupPermissions("bob", members)
+	if !perms.Can(ActionKick, "carol") {
+		t.Error("Expected manager to be able to kick a member")
+	}
+	if perms.Can(ActionKick, "alice") {
+		t.Error("Expected manager to be unable to kick the owner")
+	}
+
+	err := perms.Check(ActionSetRole, "carol")
+	var permErr *PermissionError
+	if !errors.As(err, &permErr) || !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("Expected a PermissionError matching ErrPermissionDenied, got %v", err)
+	}
+	if err := perms.Check(ActionKick, "dave"); !errors.Is(err, ErrNotGroupMember) {
+		t.Errorf("Expected ErrNotGroupMember for unknown target, got %v", err)
+	}
+
+	outsider := NewGroupPermissions("mallory", members)
+	if outsider.Can(ActionInvite, "") {
+		t.Error("Expected non-member to be unable to invite")
+	}
+}
+
+// TestGroupCheckPermissions tests that disallowed calls are stopped before sending
+func TestGroupCheckPermissions(t *testing.T) {
+	fs := newFakeServer(t)
+	owner := fs.login(t, "alice")
+	member := fs.login(t, "bob")
+	fs.createGroup(t, owner, "Team", "bob")
+
+	ctx := context.Background()
+	group := member.OpenGroup(1)
+	group.CheckPermissions = true
+	if _, err := group.Info(ctx); err != nil {
+		t.Fatalf("Info failed: %v", err)
+	}
+
+	requests := countRequests(member.client)
+	if err := group.Kick(ctx, "alice"); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("Expected ErrPermissionDenied, got %v", err)
+	}
+	if err := group.ChangeName(ctx, "Mine"); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("Expected ErrPermissionDenied, got %v", err)
+	}
+	if err := group.Disband(ctx); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("Expected ErrPermissionDenied, got %v", err)
+	}
+	if err := group.TransferOwnership(ctx, "alice"); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("Expected ErrPermissionDenied, got %v", err)
+	}
+	if *requests != 0 {
+		t.Errorf("Expected no requests for disallowed calls, got %d", *requests)
+	}
+
+	// Without local checks the server rejects the call
+	group.CheckPermissions = false
+	if err := group.Kick(ctx, "alice"); resultCode(err) != fakeCodePermissionDenied {
+		t.Errorf("Expected the server to reject the kick, got %v", err)
+	}
+}
+
+// TestGroupCheckPermissionsRestoredSession tests permission checks for clients created from a session
+func TestGroupCheckPermissionsRestoredSession(t *testing.T) {
+	fs := newFakeServer(t)
+	owner := fs.login(t, "alice")
+	fs.login(t, "bob")
+	fs.createGroup(t, owner, "Team", "bob")
+
+	restored := &User{client: NewClientWithSession(fs.URL, owner.client.Session)}
+	group := restored.OpenGroup(1)
+	group.CheckPermissions = true
+
+	ctx := context.Background()
+	perms, err := group.Permissions(ctx)
+	if err != nil {
+		t.Fatalf("Permissions failed: %v", err)
+	}
+	if perms.Self != "alice" || !perms.IsMember || perms.Role != Owner {
+		t.Errorf("Expected the restored session to act as the owner, got %+v", perms)
+	}
+	if err := group.Kick(ctx, "bob"); err != nil {
+		t.Errorf("Kick failed: %v", err)
+	}
+	if err := group.Invite(ctx, "bob"); err != nil {
+		t.Errorf("Invite failed: %v", err)
+	}
+}
@@ -0,0 +1,125 @@
+package stealthim
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// SessionInfo describes an active login session of the account
+type SessionInfo struct {
+	ID         string    `json:"id"`
+	Current    bool      `json:"current"` // Whether this is the session of the calling client
+	CreateTime Timestamp `json:"create_time"`
+	LastActive Timestamp `json:"last_active"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+}
+
+// Logout revokes the current session on the server and clears it from the client.
+// The session is cleared locally even if the server cannot be reached or does not support
+// logout, in which case the returned error says so.
+func (u *User) Logout(ctx context.Context) error {
+	defer u.client.setSession("", "")
+
+	resp, err := u.client.doRequest(ctx, "DELETE", "/api/v1/user/session", nil)
+	if err != nil {
+		return fmt.Errorf("logout request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("logout: %w", ErrNotSupported)
+	}
+
+	var response struct {
+		Result Result `json:"result"`
+	}
+	if err := u.client.parseResponse(resp, &response); err != nil {
+		return fmt.Errorf("failed to parse logout response: %w", err)
+	}
+
+	if !response.Result.IsSuccess() {
+		return response.Result.ToError()
+	}
+
+	return nil
+}
+
+// Sessions lists the active sessions of the account.
+// It returns ErrNotSupported if the server has no session management.
+func (u *User) Sessions(ctx context.Context) ([]SessionInfo, error) {
+	resp, err := u.client.doRequest(ctx, "GET", "/api/v1/user/session", nil)
+	if err != nil {
+		return nil, fmt.Errorf("list sessions request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("list sessions: %w", ErrNotSupported)
+	}
+
+	var response struct {
+		Result   Result        `json:"result"`
+		Sessions []SessionInfo `json:"sessions"`
+	}
+	if err := u.client.parseResponse(resp, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse list sessions response: %w", err)
+	}
+
+	if !response.Result.IsSuccess() {
+		return nil, response.Result.ToError()
+	}
+
+	return response.Sessions, nil
+}
+
+// RevokeSession signs out another session of the account, see Sessions.
+// It returns ErrNotSupported if the server has no session management.
+func (u *User) RevokeSession(ctx context.Context, sessionID string) error {
+	endpoint := "/api/v1/user/session/" + url.PathEscape(sessionID)
+	resp, err := u.client.doRequest(ctx, "DELETE", endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("revoke session request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("revoke session: %w", ErrNotSupported)
+	}
+
+	var response struct {
+		Result Result `json:"result"`
+	}
+	if err := u.client.parseResponse(resp, &response); err != nil {
+		return fmt.Errorf("failed to parse revoke session response: %w", err)
+	}
+
+	if !response.Result.IsSuccess() {
+		return response.Result.ToError()
+	}
+
+	return nil
+}
+
+// RevokeOtherSessions signs out every session of the account except the current one
+// and returns how many were revoked
+func (u *User) RevokeOtherSessions(ctx context.Context) (int, error) {
+	sessions, err := u.Sessions(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	revoked := 0
+	for _, session := range sessions {
+		if session.Current {
+			continue
+		}
+		if err := u.RevokeSession(ctx, session.ID); err != nil {
+			return revoked, fmt.Errorf("failed to revoke session %s: %w", session.ID, err)
+		}
+		revoked++
+	}
+	return revoked, nil
+}
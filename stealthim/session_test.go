@@ -0,0 +1,116 @@
+package stealthim
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+// TestUserLogout tests that User.Logout revokes the session on the server and clears it locally
+func TestUserLogout(t *testing.T) {
+	fs := newFakeServer(t)
+	user := fs.login(t, "alice")
+	session := user.client.Session
+
+	ctx := context.Background()
+	if err := user.Logout(ctx); err != nil {
+		t.Fatalf("Logout failed: %v", err)
+	}
+	if user.client.Session != "" {
+		t.Error("Expected the session to be cleared")
+	}
+
+	// The old session must no longer be accepted
+	user.client.Session = session
+	if _, err := user.GetSelfInfo(ctx); resultCode(err) != fakeCodeUnauthorized {
+		t.Errorf("Expected a revoked session to be rejected, got %v", err)
+	}
+}
+
+// TestUserLogoutConcurrent tests that Logout can run while other goroutines use the client
+func TestUserLogoutConcurrent(t *testing.T) {
+	fs := newFakeServer(t)
+	user := fs.login(t, "alice")
+	group := fs.createGroup(t, user, "Ops")
+
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Requests racing the logout may fail, they must just not touch the session unguarded
+			group.GetMembers(ctx)
+			group.Refresh(ctx)
+		}()
+	}
+	if err := user.Logout(ctx); err != nil {
+		t.Fatalf("Logout failed: %v", err)
+	}
+	wg.Wait()
+	if user.client.session() != "" {
+		t.Error("Expected the session to be cleared")
+	}
+}
+
+// TestUserSessions tests listing and revoking the sessions of an account
+func TestUserSessions(t *testing.T) {
+	fs := newFakeServer(t)
+	user := fs.login(t, "alice")
+	server := NewServer(fs.URL)
+	kiosk, _, err := server.Login(context.Background(), "alice", "Ab123456")
+	if err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+
+	ctx := context.Background()
+	sessions, err := user.Sessions(ctx)
+	if err != nil {
+		t.Fatalf("Sessions failed: %v", err)
+	}
+	current := 0
+	for _, s := range sessions {
+		if s.Current {
+			current++
+		}
+	}
+	if len(sessions) != 2 || current != 1 {
+		t.Fatalf("Expected 2 sessions with 1 current, got %+v", sessions)
+	}
+
+	revoked, err := user.RevokeOtherSessions(ctx)
+	if err != nil {
+		t.Fatalf("RevokeOtherSessions failed: %v", err)
+	}
+	if revoked != 1 {
+		t.Errorf("Expected 1 revoked session, got %d", revoked)
+	}
+	if _, err := kiosk.GetSelfInfo(ctx); resultCode(err) != fakeCodeUnauthorized {
+		t.Errorf("Expected the kiosk session to be revoked, got %v", err)
+	}
+	if _, err := user.GetSelfInfo(ctx); err != nil {
+		t.Errorf("Expected the current session to stay valid, got %v", err)
+	}
+}
+
+// TestUserSessionsNotSupported tests the behaviour against servers without session management
+func TestUserSessionsNotSupported(t *testing.T) {
+	fs := newFakeServer(t)
+	user := fs.login(t, "alice")
+	fs.noSessions = true
+
+	ctx := context.Background()
+	if _, err := user.Sessions(ctx); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("Expected ErrNotSupported from Sessions, got %v", err)
+	}
+	if err := user.RevokeSession(ctx, "1"); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("Expected ErrNotSupported from RevokeSession, got %v", err)
+	}
+	if err := user.Logout(ctx); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("Expected ErrNotSupported from Logout, got %v", err)
+	}
+	if user.client.Session != "" {
+		t.Error("Expected the session to be cleared locally anyway")
+	}
+}
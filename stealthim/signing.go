@@ -0,0 +1,288 @@
+package stealthim
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Signing errors
+var (
+	ErrUnknownSigningKey = errors.New("no signing key registered for user")
+	ErrInvalidSignature  = errors.New("invalid message signature")
+	ErrUnsignableType    = errors.New("only text messages can be signed")
+)
+
+// signedMessagePrefix marks a signed message. The envelope is
+// "stim-sig:1:<signature>:<unix ms>:<content>", so clients without signing support still
+// see the content at the end.
+const signedMessagePrefix = "stim-sig:1:"
+
+// DefaultMaxClockSkew is how far the signed time may be from the server time of a message
+const DefaultMaxClockSkew = 5 * time.Minute
+
+// VerificationStatus is the outcome of checking a message signature
+type VerificationStatus int
+
+// Verification statuses
+const (
+	Unsigned         VerificationStatus = iota // The message carries no signature
+	Verified                                   // The signature matches the sender's registered key
+	InvalidSignature                           // The signature is malformed, forged or stale
+	UnknownSigner                              // The sender has no registered key to check against
+)
+
+// String returns the name of the status
+func (s VerificationStatus) String() string {
+	switch s {
+	case Unsigned:
+		return "unsigned"
+	case Verified:
+		return "verified"
+	case InvalidSignature:
+		return "invalid"
+	case UnknownSigner:
+		return "unknown signer"
+	default:
+		return fmt.Sprintf("VerificationStatus(%d)", int(s))
+	}
+}
+
+// SigningKeyStore looks up the registered Ed25519 public keys of users
+type SigningKeyStore interface {
+	// SigningKey returns the public key of username, or ErrUnknownSigningKey if there is none
+	SigningKey(ctx context.Context, username string) (ed25519.PublicKey, error)
+}
+
+// Keyring is an in-memory SigningKeyStore, filled by the app from a source it trusts
+type Keyring struct {
+	mu   sync.RWMutex
+	keys map[string]ed25519.PublicKey
+}
+
+// NewKeyring creates an empty keyring
+func NewKeyring() *Keyring {
+	return &Keyring{keys: make(map[string]ed25519.PublicKey)}
+}
+
+// Add registers the public key of username, replacing any previous key
+func (k *Keyring) Add(username string, publicKey ed25519.PublicKey) error {
+	if len(publicKey) != ed25519.PublicKeySize {
+		return fmt.Errorf("invalid Ed25519 public key size %d", len(publicKey))
+	}
+	k.mu.Lock()
+	k.keys[username] = append(ed25519.PublicKey(nil), publicKey...)
+	k.mu.Unlock()
+	return nil
+}
+
+// Remove forgets the public key of username
+func (k *Keyring) Remove(username string) {
+	k.mu.Lock()
+	delete(k.keys, username)
+	k.mu.Unlock()
+}
+
+// SigningKey returns the registered public key of username
+func (k *Keyring) SigningKey(ctx context.Context, username string) (ed25519.PublicKey, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	key, ok := k.keys[username]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownSigningKey, username)
+	}
+	return key, nil
+}
+
+// VerifiedMessage is a received message after signature verification
+type VerifiedMessage struct {
+	Message                     // Msg holds the content without the signature envelope
+	Status   VerificationStatus // Whether the sender could be authenticated
+	SignedAt time.Time          // Time claimed by the signer, zero for unsigned messages
+	Err      error              // Why the message is not Verified, nil for unsigned messages
+}
+
+// SignedGroup wraps a Group so that sent messages are signed with the user's Ed25519 key and
+// received messages are checked against the sender's registered key.
+//
+// The signature covers the group ID, message type, content and signing time, so a message
+// cannot be moved to another group, altered or attributed to another user by the server.
+// A signature seen on another message before is rejected as a replay.
+// Bots should only act on messages with status Verified.
+//
+// Only text messages can be signed, since the server reads the content of other types,
+// for example the file hash of a File message.
+type SignedGroup struct {
+	group *Group
+	key   ed25519.PrivateKey
+	keys  SigningKeyStore
+
+	// MaxClockSkew limits how far the signed time may be from the server time of a message,
+	// which stops old signed messages from being replayed. Seen signatures are forgotten once
+	// they are older than this. Zero disables the check and keeps all seen signatures.
+	MaxClockSkew time.Duration
+
+	mu   sync.Mutex
+	seen map[string]seenSignature // Verified signatures, to detect replays
+}
+
+// seenSignature records the message a signature was first verified on
+type seenSignature struct {
+	msgID    string
+	signedAt time.Time
+}
+
+// NewSignedGroup enables message signing for group. key may be nil to only verify messages.
+func NewSignedGroup(group *Group, key ed25519.PrivateKey, keys SigningKeyStore) *SignedGroup {
+	return &SignedGroup{
+		group:        group,
+		key:          key,
+		keys:         keys,
+		MaxClockSkew: DefaultMaxClockSkew,
+		seen:         make(map[string]seenSignature),
+	}
+}
+
+// Group returns the underlying group
+func (s *SignedGroup) Group() *Group {
+	return s.group
+}
+
+// PublicKey returns the public key other members need to register to verify our messages
+func (s *SignedGroup) PublicKey() ed25519.PublicKey {
+	if s.key == nil {
+		return nil
+	}
+	return s.key.Public().(ed25519.PublicKey)
+}
+
+// SendText sends a signed text message
+func (s *SignedGroup) SendText(ctx context.Context, message string) (*SentMessage, error) {
+	return s.SendMessage(ctx, Text, message)
+}
+
+// SendMessage signs content and sends it. The receipt holds the signed envelope as Msg.
+// msgType must be Text, other types return ErrUnsignableType.
+func (s *SignedGroup) SendMessage(ctx context.Context, msgType MessageType, content string) (*SentMessage, error) {
+	if s.key == nil {
+		return nil, errors.New("signed group has no signing key")
+	}
+	if msgType != Text {
+		return nil, fmt.Errorf("%w: type %d", ErrUnsignableType, msgType)
+	}
+
+	signedAt := time.Now()
+	signature := ed25519.Sign(s.key, signedData(s.group.GroupID, msgType, signedAt, content))
+	envelope := signedMessagePrefix + base64.RawURLEncoding.EncodeToString(signature) + ":" +
+		strconv.FormatInt(signedAt.UnixMilli(), 10) + ":" + content
+	return s.group.SendMessage(ctx, msgType, envelope)
+}
+
+// ReceiveMessages receives group messages and verifies their signatures
+func (s *SignedGroup) ReceiveMessages(ctx context.Context, opts *ReceiveMessageOptions) (<-chan VerifiedMessage, <-chan error) {
+	messages, errs := s.group.ReceiveMessages(ctx, opts)
+	out := make(chan VerifiedMessage)
+
+	go func() {
+		defer close(out)
+		for msg := range messages {
+			select {
+			case out <- s.Verify(ctx, msg):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out, errs
+}
+
+// Verify checks the signature of a message received through Group.ReceiveMessages
+func (s *SignedGroup) Verify(ctx context.Context, msg Message) VerifiedMessage {
+	result := VerifiedMessage{Message: msg, Status: Unsigned}
+	if !strings.HasPrefix(msg.Msg, signedMessagePrefix) {
+		return result
+	}
+
+	fields := strings.SplitN(strings.TrimPrefix(msg.Msg, signedMessagePrefix), ":", 3)
+	if len(fields) != 3 {
+		result.Status, result.Err = InvalidSignature, fmt.Errorf("%w: malformed envelope", ErrInvalidSignature)
+		return result
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(fields[0])
+	if err != nil {
+		result.Status, result.Err = InvalidSignature, fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+		return result
+	}
+	millis, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		result.Status, result.Err = InvalidSignature, fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+		return result
+	}
+	result.Msg = fields[2]
+	result.SignedAt = time.UnixMilli(millis)
+
+	publicKey, err := s.keys.SigningKey(ctx, msg.Username)
+	if err != nil {
+		result.Status, result.Err = UnknownSigner, err
+		return result
+	}
+	data := signedData(s.group.GroupID, MessageType(msg.Type), result.SignedAt, result.Msg)
+	if len(publicKey) != ed25519.PublicKeySize || !ed25519.Verify(publicKey, data, signature) {
+		result.Status, result.Err = InvalidSignature, ErrInvalidSignature
+		return result
+	}
+	if s.MaxClockSkew > 0 && !msg.Time.IsZero() {
+		if skew := msg.Time.Sub(result.SignedAt).Abs(); skew > s.MaxClockSkew {
+			result.Status, result.Err = InvalidSignature, fmt.Errorf("%w: signed %s away from the server time", ErrInvalidSignature, skew)
+			return result
+		}
+	}
+	if !s.remember(string(signature), msg.MsgID, result.SignedAt) {
+		result.Status, result.Err = InvalidSignature, fmt.Errorf("%w: signature replayed from another message", ErrInvalidSignature)
+		return result
+	}
+
+	result.Status = Verified
+	return result
+}
+
+// remember records a verified signature and reports whether it is new or belongs to the same
+// message, which is delivered again for example after a reconnect
+func (s *SignedGroup) remember(signature, msgID string, signedAt time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.MaxClockSkew > 0 {
+		// Replays of older signatures fail the clock skew check
+		for sig, seen := range s.seen {
+			if time.Since(seen.signedAt) > s.MaxClockSkew {
+				delete(s.seen, sig)
+			}
+		}
+	}
+	if seen, ok := s.seen[signature]; ok {
+		return seen.msgID == msgID
+	}
+	if s.seen == nil {
+		s.seen = make(map[string]seenSignature)
+	}
+	s.seen[signature] = seenSignature{msgID: msgID, signedAt: signedAt}
+	return true
+}
+
+// signedData builds the bytes covered by a message signature
+func signedData(groupID int64, msgType MessageType, signedAt time.Time, content string) []byte {
+	data := []byte("stealthim/sig/v1\x00")
+	data = binary.BigEndian.AppendUint64(data, uint64(groupID))
+	data = binary.BigEndian.AppendUint32(data, uint32(msgType))
+	data = binary.BigEndian.AppendUint64(data, uint64(signedAt.UnixMilli()))
+	return append(data, content...)
+}
@@ -0,0 +1,105 @@
+package stealthim
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestSignedGroup tests signing, verification and the detection of forged messages
+func TestSignedGroup(t *testing.T) {
+	fs := newFakeServer(t)
+	aliceUser := fs.login(t, "alice")
+	bobUser := fs.login(t, "bob")
+	carolUser := fs.login(t, "carol")
+	group := fs.createGroup(t, aliceUser, "Bots", "bob", "carol")
+	ctx := context.Background()
+
+	alicePub, aliceKey, _ := ed25519.GenerateKey(rand.Reader)
+	_, carolKey, _ := ed25519.GenerateKey(rand.Reader)
+	keyring := NewKeyring()
+	if err := keyring.Add("alice", alicePub); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	alice := NewSignedGroup(&Group{client: aliceUser.client, GroupID: group.GroupID}, aliceKey, keyring)
+	carol := NewSignedGroup(&Group{client: carolUser.client, GroupID: group.GroupID}, carolKey, keyring)
+	bob := NewSignedGroup(&Group{client: bobUser.client, GroupID: group.GroupID}, nil, keyring)
+
+	if _, err := alice.SendText(ctx, "/deploy prod"); err != nil {
+		t.Fatalf("SendText failed: %v", err)
+	}
+	if _, err := carol.SendText(ctx, "/deploy prod"); err != nil {
+		t.Fatalf("SendText failed: %v", err)
+	}
+	if _, err := bob.Group().SendText(ctx, "hello"); err != nil {
+		t.Fatalf("SendText failed: %v", err)
+	}
+	if _, err := bob.SendText(ctx, "hello"); err == nil {
+		t.Error("Expected sending without a key to fail")
+	}
+	if _, err := alice.SendMessage(ctx, File, "hash"); !errors.Is(err, ErrUnsignableType) {
+		t.Errorf("Expected ErrUnsignableType for a file message, got %v", err)
+	}
+
+	raw := receiveRaw(t, bob.Group(), 3)
+	verified := bob.Verify(ctx, raw[0])
+	if verified.Status != Verified || verified.Err != nil || verified.Msg != "/deploy prod" || verified.SignedAt.IsZero() {
+		t.Errorf("Expected alice's message to be verified, got %+v", verified)
+	}
+	if v := bob.Verify(ctx, raw[1]); v.Status != UnknownSigner || !errors.Is(v.Err, ErrUnknownSigningKey) || v.Msg != "/deploy prod" {
+		t.Errorf("Expected carol to be an unknown signer, got %+v", v)
+	}
+	if v := bob.Verify(ctx, raw[2]); v.Status != Unsigned || v.Err != nil || v.Msg != "hello" {
+		t.Errorf("Expected an unsigned message, got %+v", v)
+	}
+
+	forgeries := map[string]func(*Message){
+		"impersonated sender": func(m *Message) { m.Username = "alice"; m.Msg = raw[1].Msg },
+		"altered content":     func(m *Message) { m.Msg = m.Msg[:len(m.Msg)-4] + "test" },
+		"changed type":        func(m *Message) { m.Type = int(Image) },
+		"replayed later":      func(m *Message) { m.Time = Timestamp{Time: m.Time.Add(time.Hour)} },
+		"malformed":           func(m *Message) { m.Msg = signedMessagePrefix + "nope" },
+	}
+	for name, forge := range forgeries {
+		msg := raw[0]
+		forge(&msg)
+		if v := bob.Verify(ctx, msg); v.Status != InvalidSignature || !errors.Is(v.Err, ErrInvalidSignature) {
+			t.Errorf("%s: expected an invalid signature, got %v (%v)", name, v.Status, v.Err)
+		}
+	}
+
+	// The same message may be delivered again, but not be replayed as a new one
+	if v := bob.Verify(ctx, raw[0]); v.Status != Verified {
+		t.Errorf("Expected a repeated delivery to stay verified, got %v (%v)", v.Status, v.Err)
+	}
+	replayed := raw[0]
+	replayed.MsgID += "-replay"
+	if v := bob.Verify(ctx, replayed); v.Status != InvalidSignature || !errors.Is(v.Err, ErrInvalidSignature) {
+		t.Errorf("Expected a replayed message to be rejected, got %v (%v)", v.Status, v.Err)
+	}
+
+	// A signature is bound to its group
+	other := NewSignedGroup(&Group{client: bobUser.client, GroupID: group.GroupID + 1}, nil, keyring)
+	if v := other.Verify(ctx, raw[0]); v.Status != InvalidSignature {
+		t.Errorf("Expected a message moved to another group to be rejected, got %v", v.Status)
+	}
+}
+
+// TestVerificationStatusString tests the names of verification statuses
+func TestVerificationStatusString(t *testing.T) {
+	for status, want := range map[VerificationStatus]string{
+		Unsigned:              "unsigned",
+		Verified:              "verified",
+		InvalidSignature:      "invalid",
+		UnknownSigner:         "unknown signer",
+		VerificationStatus(9): "VerificationStatus(9)",
+	} {
+		if got := status.String(); got != want {
+			t.Errorf("Expected %q, got %q", want, got)
+		}
+	}
+}
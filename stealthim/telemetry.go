@@ -0,0 +1,137 @@
+package stealthim
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName identifies the SDK as an OpenTelemetry instrumentation scope
+const instrumentationName = "github.com/StealthIM/StealthIMGoSDK/stealthim"
+
+// Transfer directions recorded on the bytes transferred counter
+const (
+	directionUpload   = "upload"
+	directionDownload = "download"
+)
+
+// telemetry holds the tracer and metric instruments of a client
+type telemetry struct {
+	tracer          trace.Tracer
+	propagator      propagation.TextMapPropagator
+	requestDuration metric.Float64Histogram
+	requestErrors   metric.Int64Counter
+	bytesTransfer   metric.Int64Counter
+	subscriptions   metric.Int64UpDownCounter
+}
+
+// telemetry returns the client's instrumentation, creating it on first use.
+// TracerProvider, MeterProvider and Propagator must be set before the first request.
+func (c *Client) telemetry() *telemetry {
+	c.telemetryOnce.Do(func() {
+		tp := c.TracerProvider
+		if tp == nil {
+			tp = otel.GetTracerProvider()
+		}
+		mp := c.MeterProvider
+		if mp == nil {
+			mp = otel.GetMeterProvider()
+		}
+		prop := c.Propagator
+		if prop == nil {
+			prop = otel.GetTextMapPropagator()
+		}
+
+		meter := mp.Meter(instrumentationName)
+		t := &telemetry{
+			tracer:     tp.Tracer(instrumentationName),
+			propagator: prop,
+		}
+
+		// Instrument creation only fails for invalid names, so errors are reported to the global handler
+		var err error
+		if t.requestDuration, err = meter.Float64Histogram("stealthim.client.request.duration",
+			metric.WithDescription("Duration of StealthIM API requests"),
+			metric.WithUnit("s")); err != nil {
+			otel.Handle(err)
+		}
+		if t.requestErrors, err = meter.Int64Counter("stealthim.client.request.errors",
+			metric.WithDescription("Failed StealthIM API requests by result code"),
+			metric.WithUnit("{request}")); err != nil {
+			otel.Handle(err)
+		}
+		if t.bytesTransfer, err = meter.Int64Counter("stealthim.client.transfer.bytes",
+			metric.WithDescription("File bytes transferred"),
+			metric.WithUnit("By")); err != nil {
+			otel.Handle(err)
+		}
+		if t.subscriptions, err = meter.Int64UpDownCounter("stealthim.client.subscriptions.active",
+			metric.WithDescription("Active message subscriptions"),
+			metric.WithUnit("{subscription}")); err != nil {
+			otel.Handle(err)
+		}
+		c.tel = t
+	})
+	return c.tel
+}
+
+// startSpan starts a client span for an SDK operation
+func (t *telemetry) startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return t.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+// userRoutes are the fixed paths below /api/v1/user, anything else there is a username
+var userRoutes = map[string]bool{
+	"email": true, "nickname": true, "phone": true, "password": true, "register": true, "session": true,
+}
+
+// routeTemplate replaces the IDs, usernames and hashes in an API path with placeholders,
+// so span names stay low-cardinality, e.g. /api/v1/group/42/kick becomes /api/v1/group/{id}/kick
+func routeTemplate(endpoint string) string {
+	path, _, _ := strings.Cut(endpoint, "?")
+	segments := strings.Split(path, "/")
+	// segments[0] is empty, followed by "api", "v1" and the resource
+	if len(segments) < 5 || segments[1] != "api" {
+		return path
+	}
+	switch resource := segments[3]; {
+	case resource == "file":
+		segments[4] = "{hash}"
+	case resource == "user" && !userRoutes[segments[4]]:
+		segments[4] = "{username}"
+	case resource == "user" && segments[4] == "session" && len(segments) > 5:
+		segments[5] = "{id}"
+	case resource == "group" || resource == "message":
+		if _, err := strconv.ParseInt(segments[4], 10, 64); err == nil {
+			segments[4] = "{id}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// inject propagates the trace context of ctx into the outgoing headers
+func (t *telemetry) inject(ctx context.Context, header http.Header) {
+	t.propagator.Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+// recordError marks the span as failed and counts the error by result code.
+// Transport failures without a result code are recorded with code 0.
+func (t *telemetry) recordError(ctx context.Context, span trace.Span, err error, code int, attrs ...attribute.KeyValue) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	attrs = append(attrs, attribute.Int("stealthim.result.code", code))
+	t.requestErrors.Add(ctx, 1, metric.WithAttributes(attrs...))
+}
+
+// recordBytes adds transferred file bytes for the given direction
+func (t *telemetry) recordBytes(ctx context.Context, direction string, n int64) {
+	t.bytesTransfer.Add(ctx, n, metric.WithAttributes(attribute.String("stealthim.transfer.direction", direction)))
+}
@@ -0,0 +1,170 @@
+package stealthim
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// newInstrumentedClient creates a client wired to in-memory trace and metric exporters
+func newInstrumentedClient(baseURL string) (*Client, *tracetest.InMemoryExporter, *sdkmetric.ManualReader) {
+	exporter := tracetest.NewInMemoryExporter()
+	reader := sdkmetric.NewManualReader()
+
+	client := NewClientWithSession(baseURL, "test-session")
+	client.TracerProvider = sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	client.MeterProvider = sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	client.Propagator = propagation.TraceContext{}
+	return client, exporter, reader
+}
+
+// findMetric looks up a collected metric by name
+func findMetric(t *testing.T, reader *sdkmetric.ManualReader, name string) *metricdata.Metrics {
+	t.Helper()
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("Failed to collect metrics: %v", err)
+	}
+	for _, sm := range rm.ScopeMetrics {
+		for i := range sm.Metrics {
+			if sm.Metrics[i].Name == name {
+				return &sm.Metrics[i]
+			}
+		}
+	}
+	return nil
+}
+
+// TestTelemetryRequestSpan tests that doRequest creates a span and propagates trace context
+func TestTelemetryRequestSpan(t *testing.T) {
+	var traceparent string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("Traceparent")
+		w.Write([]byte(`{"result":{"code":800,"msg":""},"groups":[1,2]}`))
+	}))
+	defer ts.Close()
+
+	client, exporter, reader := newInstrumentedClient(ts.URL)
+	user := &User{client: client}
+	if _, err := user.GetGroups(context.Background()); err != nil {
+		t.Fatalf("GetGroups failed: %v", err)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("Expected 1 span, got %d", len(spans))
+	}
+	if spans[0].Name != "StealthIM GET /api/v1/group" {
+		t.Errorf("Unexpected span name: %s", spans[0].Name)
+	}
+	if traceparent == "" {
+		t.Error("Expected traceparent header to be propagated")
+	}
+
+	if m := findMetric(t, reader, "stealthim.client.request.duration"); m == nil {
+		t.Error("Expected request duration metric to be recorded")
+	}
+}
+
+// TestTelemetryRequestErrorCode tests that failed results are counted by code
+func TestTelemetryRequestErrorCode(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"result":{"code":1302,"msg":"permission denied"}}`))
+	}))
+	defer ts.Close()
+
+	client, exporter, reader := newInstrumentedClient(ts.URL)
+	group := &Group{client: client, GroupID: 7}
+	if err := group.Kick(context.Background(), "bob"); err == nil {
+		t.Fatal("Expected kick to fail")
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 || spans[0].Status.Code != codes.Error {
+		t.Fatalf("Expected a single failed span, got %+v", spans)
+	}
+	if spans[0].Name != "StealthIM POST /api/v1/group/{id}/kick" {
+		t.Errorf("Unexpected span name: %s", spans[0].Name)
+	}
+
+	m := findMetric(t, reader, "stealthim.client.request.errors")
+	if m == nil {
+		t.Fatal("Expected request errors metric to be recorded")
+	}
+	sum, ok := m.Data.(metricdata.Sum[int64])
+	if !ok || len(sum.DataPoints) != 1 {
+		t.Fatalf("Unexpected request errors data: %+v", m.Data)
+	}
+	code, _ := sum.DataPoints[0].Attributes.Value(attribute.Key("stealthim.result.code"))
+	if code.AsInt64() != 1302 {
+		t.Errorf("Expected error code 1302, got %d", code.AsInt64())
+	}
+}
+
+// TestTelemetryActiveSubscriptions tests that ReceiveMessages tracks active subscriptions
+func TestTelemetryActiveSubscriptions(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"result\":{\"code\":800,\"msg\":\"\"},\"msg\":[{\"msgid\":\"1\",\"msg\":\"hi\"}]}\n\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+
+	client, exporter, reader := newInstrumentedClient(ts.URL)
+	group := &Group{client: client, GroupID: 7}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	msgChan, errChan := group.ReceiveMessages(ctx, DefaultReceiveMessageOptions())
+	if msg := <-msgChan; msg.MsgID != "1" {
+		t.Fatalf("Unexpected message: %+v", msg)
+	}
+
+	m := findMetric(t, reader, "stealthim.client.subscriptions.active")
+	if m == nil {
+		t.Fatal("Expected active subscriptions metric to be recorded")
+	}
+	if sum := m.Data.(metricdata.Sum[int64]); sum.DataPoints[0].Value != 1 {
+		t.Errorf("Expected 1 active subscription, got %d", sum.DataPoints[0].Value)
+	}
+
+	cancel()
+	for range msgChan {
+	}
+	for range errChan {
+	}
+
+	if len(exporter.GetSpans()) == 0 {
+		t.Error("Expected a span for the message stream connection")
+	}
+}
+
+// TestRouteTemplate tests that span names leave out IDs, usernames and hashes
+func TestRouteTemplate(t *testing.T) {
+	for endpoint, want := range map[string]string{
+		"/api/v1/group":                 "/api/v1/group",
+		"/api/v1/group/42/kick":         "/api/v1/group/{id}/kick",
+		"/api/v1/message/7?msgid=100":   "/api/v1/message/{id}",
+		"/api/v1/user":                  "/api/v1/user",
+		"/api/v1/user/alice":            "/api/v1/user/{username}",
+		"/api/v1/user/nickname":         "/api/v1/user/nickname",
+		"/api/v1/user/session/abc":      "/api/v1/user/session/{id}",
+		"/api/v1/file/0123456789abcdef": "/api/v1/file/{hash}",
+		"/api/v1/ping":                  "/api/v1/ping",
+	} {
+		if got := routeTemplate(endpoint); got != want {
+			t.Errorf("routeTemplate(%q) = %q, want %q", endpoint, got, want)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package stealthim
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Timestamp is a point in time as reported by the server. It accepts Unix seconds or
+// milliseconds as a number or string, RFC 3339 and "2006-01-02 15:04:05". Other values
+// give the zero time, so an unknown format never fails decoding the surrounding response.
+type Timestamp struct {
+	time.Time
+	Raw string // The value as sent by the server, set when it could not be parsed
+}
+
+// timestampLayouts are the textual formats accepted besides Unix timestamps
+var timestampLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05"}
+
+// UnmarshalJSON parses any of the supported formats. Empty and unknown values give the zero
+// time, unknown ones are kept in Raw.
+func (t *Timestamp) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if string(data) == "null" {
+		return nil
+	}
+
+	text := string(data)
+	if len(data) > 0 && data[0] == '"' {
+		// Fall back to the quoted text if it is not a valid JSON string
+		json.Unmarshal(data, &text)
+	}
+	parsed, err := parseTimestamp(text)
+	if err != nil {
+		t.Time, t.Raw = time.Time{}, text
+		return nil
+	}
+	t.Time, t.Raw = parsed, ""
+	return nil
+}
+
+// MarshalJSON encodes the time as a string of Unix seconds, like the server does.
+// A value that could not be parsed is encoded as received.
+func (t Timestamp) MarshalJSON() ([]byte, error) {
+	if t.IsZero() {
+		return json.Marshal(t.Raw)
+	}
+	return json.Marshal(strconv.FormatInt(t.Unix(), 10))
+}
+
+// parseTimestamp parses a Unix timestamp or one of timestampLayouts
+func parseTimestamp(text string) (time.Time, error) {
+	if text == "" || text == "0" {
+		return time.Time{}, nil
+	}
+	if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+		// Values this large are milliseconds, seconds would be far in the future
+		if n > 1e12 || n < -1e12 {
+			return time.UnixMilli(n), nil
+		}
+		return time.Unix(n, 0), nil
+	}
+	for _, layout := range timestampLayouts {
+		if parsed, err := time.Parse(layout, text); err == nil {
+			return parsed, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp %q", text)
+}
+
+// VIPLevel is the VIP tier of a user, 0 for regular users
+type VIPLevel int
+
+// VIPNone is the level of users without VIP status
+const VIPNone VIPLevel = 0
+
+// IsVIP reports whether the level grants VIP status
+func (l VIPLevel) IsVIP() bool {
+	return l > VIPNone
+}
+
+// String returns a readable name of the level
+func (l VIPLevel) String() string {
+	if !l.IsVIP() {
+		return "none"
+	}
+	return fmt.Sprintf("VIP %d", int(l))
+}
@@ -0,0 +1,78 @@
+package stealthim
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// TestTimestampUnmarshal tests the accepted Timestamp formats
+func TestTimestampUnmarshal(t *testing.T) {
+	expected := time.Unix(1700000000, 0)
+	tests := []string{
+		`"1700000000"`,
+		`1700000000`,
+		`"1700000000000"`,
+		`"2023-11-14T22:13:20Z"`,
+		`"2023-11-14 22:13:20"`,
+	}
+	for _, input := range tests {
+		var ts Timestamp
+		if err := json.Unmarshal([]byte(input), &ts); err != nil {
+			t.Errorf("%s: unexpected error: %v", input, err)
+			continue
+		}
+		if !ts.Equal(expected) {
+			t.Errorf("%s: expected %v, got %v", input, expected, ts.Time)
+		}
+	}
+
+	for _, input := range []string{`""`, `null`, `"0"`} {
+		var ts Timestamp
+		if err := json.Unmarshal([]byte(input), &ts); err != nil || !ts.IsZero() {
+			t.Errorf("%s: expected the zero time, got %v (%v)", input, ts.Time, err)
+		}
+	}
+
+	// Unknown formats must not fail the whole response
+	var msg Message
+	if err := json.Unmarshal([]byte(`{"msgid":"1","time":"yesterday"}`), &msg); err != nil || msg.MsgID != "1" {
+		t.Fatalf("Expected the message to decode, got %+v (%v)", msg, err)
+	}
+	if !msg.Time.IsZero() || msg.Time.Raw != "yesterday" {
+		t.Errorf("Expected the zero time with the raw value, got %+v", msg.Time)
+	}
+	if data, err := json.Marshal(msg.Time); err != nil || string(data) != `"yesterday"` {
+		t.Errorf("Expected the raw value to be encoded, got %s (%v)", data, err)
+	}
+}
+
+// TestTimestampRoundTrip tests that encoded messages decode to the same time
+func TestTimestampRoundTrip(t *testing.T) {
+	msg := Message{MsgID: "1", Time: Timestamp{Time: time.Unix(1700000000, 0)}}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var decoded Message
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if !decoded.Time.Equal(msg.Time.Time) {
+		t.Errorf("Expected %v, got %v", msg.Time, decoded.Time)
+	}
+}
+
+// TestVIPLevel tests the VIPLevel type
+func TestVIPLevel(t *testing.T) {
+	var info UserInfo
+	if err := json.Unmarshal([]byte(`{"vip": 2}`), &info); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if !info.VIP.IsVIP() || info.VIP.String() != "VIP 2" {
+		t.Errorf("Unexpected VIP level %v", info.VIP)
+	}
+	if VIPNone.IsVIP() || VIPNone.String() != "none" {
+		t.Errorf("Unexpected VIPNone %v", VIPNone)
+	}
+}
@@ -1,5 +1,7 @@
 package stealthim
 
+import "fmt"
+
 // Result represents the API response result
 type Result struct {
 	Code int    `json:"code"`
@@ -8,12 +10,12 @@ type Result struct {
 
 // UserInfo represents user information
 type UserInfo struct {
-	Username    string `json:"username"`
-	Nickname    string `json:"nickname"`
-	Email       string `json:"email"`
-	PhoneNumber string `json:"phone_number"`
-	VIP         int    `json:"vip"`
-	CreateTime  string `json:"create_time"`
+	Username    string    `json:"username"`
+	Nickname    string    `json:"nickname"`
+	Email       string    `json:"email"`
+	PhoneNumber string    `json:"phone_number"`
+	VIP         VIPLevel  `json:"vip"`
+	CreateTime  Timestamp `json:"create_time"`
 }
 
 // GroupMember represents a group member
@@ -24,13 +26,14 @@ type GroupMember struct {
 
 // Message represents a message
 type Message struct {
-	GroupID  string `json:"groupid"`
-	Msg      string `json:"msg"`
-	MsgID    string `json:"msgid"`
-	Time     string `json:"time"`
-	Type     int    `json:"type"`
-	Username string `json:"username"`
-	Hash     string `json:"hash,omitempty"`
+	GroupID  string    `json:"groupid"`
+	Msg      string    `json:"msg"`
+	MsgID    string    `json:"msgid"`
+	Time     Timestamp `json:"time"`
+	Type     int       `json:"type"`
+	Username string    `json:"username"`
+	Hash     string    `json:"hash,omitempty"`
+	ClientID string    `json:"client_id,omitempty"` // ID chosen by the sender, only echoed by servers that de-duplicate sends
 }
 
 // MessageType represents the type of message
@@ -58,6 +61,20 @@ const (
 	Owner   GroupMemberType = 2
 )
 
+// String returns the name of the member type
+func (t GroupMemberType) String() string {
+	switch t {
+	case Member:
+		return "member"
+	case Manager:
+		return "manager"
+	case Owner:
+		return "owner"
+	default:
+		return fmt.Sprintf("member type %d", int(t))
+	}
+}
+
 // FileMetadata represents file metadata for upload
 type FileMetadata struct {
 	Size     string `json:"size"`
@@ -3,15 +3,21 @@ package stealthim
 import (
 	"context"
 	"fmt"
+	"sync"
 )
 
 // User represents an authenticated user
 type User struct {
 	client *Client
 	info   UserInfo
+
+	mu            sync.Mutex
+	pendingDelete *DeleteConfirmation // Set by RequestDelete, consumed by Delete
+	listeners     map[int]InfoChangeFunc
+	nextListener  int
 }
 
-// GetSelfInfo retrieves the current user's information
+// GetSelfInfo retrieves the current user's information and updates the cached profile
 func (u *User) GetSelfInfo(ctx context.Context) (*UserInfo, error) {
 	resp, err := u.client.doRequest(ctx, "GET", "/api/v1/user", nil)
 	if err != nil {
@@ -31,6 +37,8 @@ func (u *User) GetSelfInfo(ctx context.Context) (*UserInfo, error) {
 		return nil, response.Result.ToError()
 	}
 
+	u.setInfo(response.UserInfo)
+
 	return &response.UserInfo, nil
 }
 
@@ -58,8 +66,8 @@ func (u *User) GetUserInfo(ctx context.Context, username string) (*UserInfo, err
 	return &response.UserInfo, nil
 }
 
-// ChangePassword updates the user's password
-func (u *User) ChangePassword(ctx context.Context, newPassword string) error {
+// changePassword sets a new password without checking the current one, see ChangePassword
+func (u *User) changePassword(ctx context.Context, newPassword string) error {
 	reqBody := map[string]any{
 		"password": newPassword,
 	}
@@ -84,8 +92,14 @@ func (u *User) ChangePassword(ctx context.Context, newPassword string) error {
 	return nil
 }
 
-// ChangeEmail updates the user's email
+// ChangeEmail updates the user's email. Like other profile fields it only needs the session:
+// the password is re-checked for changes that lock the owner out, the password itself and
+// deleting the account. Apps that treat the email as a recovery address can call VerifyPassword first.
 func (u *User) ChangeEmail(ctx context.Context, newEmail string) error {
+	if err := ValidateEmail(newEmail); err != nil {
+		return err
+	}
+
 	reqBody := map[string]any{
 		"email": newEmail,
 	}
@@ -107,6 +121,10 @@ func (u *User) ChangeEmail(ctx context.Context, newEmail string) error {
 		return response.Result.ToError()
 	}
 
+	u.updateInfo(func(info *UserInfo) {
+		info.Email = newEmail
+	})
+
 	return nil
 }
 
@@ -133,11 +151,20 @@ func (u *User) ChangeNickname(ctx context.Context, newNickname string) error {
 		return response.Result.ToError()
 	}
 
+	u.updateInfo(func(info *UserInfo) {
+		info.Nickname = newNickname
+	})
+
 	return nil
 }
 
-// ChangePhoneNumber updates the user's phone number
+// ChangePhoneNumber updates the user's phone number. The current password is not checked,
+// see ChangeEmail for why and how to add the check.
 func (u *User) ChangePhoneNumber(ctx context.Context, newPhoneNumber string) error {
+	if err := ValidatePhoneNumber(newPhoneNumber); err != nil {
+		return err
+	}
+
 	reqBody := map[string]any{
 		"phone_number": newPhoneNumber,
 	}
@@ -159,15 +186,25 @@ func (u *User) ChangePhoneNumber(ctx context.Context, newPhoneNumber string) err
 		return response.Result.ToError()
 	}
 
+	u.updateInfo(func(info *UserInfo) {
+		info.PhoneNumber = newPhoneNumber
+	})
+
 	return nil
 }
 
-// UpdateInfo updates multiple user fields at once
-func (u *User) UpdateInfo(ctx context.Context, password, email, nickname, phoneNumber string) error {
-	reqBody := map[string]any{}
-	if password != "" {
-		reqBody["password"] = password
+// UpdateInfo updates multiple user fields at once. Empty fields are left unchanged, use Patch to clear a field.
+// The password can only be changed through ChangePassword, which checks the current one;
+// the profile fields changed here do not need it, see ChangeEmail.
+func (u *User) UpdateInfo(ctx context.Context, email, nickname, phoneNumber string) error {
+	if err := validateFields(map[string]string{
+		"email":        email,
+		"phone_number": phoneNumber,
+	}); err != nil {
+		return err
 	}
+
+	reqBody := map[string]any{}
 	if email != "" {
 		reqBody["email"] = email
 	}
@@ -195,11 +232,28 @@ func (u *User) UpdateInfo(ctx context.Context, password, email, nickname, phoneN
 		return response.Result.ToError()
 	}
 
+	u.updateInfo(func(info *UserInfo) {
+		if email != "" {
+			info.Email = email
+		}
+		if nickname != "" {
+			info.Nickname = nickname
+		}
+		if phoneNumber != "" {
+			info.PhoneNumber = phoneNumber
+		}
+	})
+
 	return nil
 }
 
-// Delete deletes the user account
-func (u *User) Delete(ctx context.Context) error {
+// Delete deletes the user account. It needs a confirmation from RequestDelete,
+// so the account cannot be removed by an accidental call.
+func (u *User) Delete(ctx context.Context, confirm *DeleteConfirmation) error {
+	if err := u.consumeDeleteConfirmation(confirm); err != nil {
+		return err
+	}
+
 	resp, err := u.client.doRequest(ctx, "DELETE", "/api/v1/user", nil)
 	if err != nil {
 		return fmt.Errorf("delete user request failed: %w", err)
@@ -242,3 +296,31 @@ func (u *User) GetGroups(ctx context.Context) ([]int64, error) {
 
 	return response.Groups, nil
 }
+
+// OpenGroup returns a Group for an existing group ID without contacting the server.
+// Use Group.Info to load its metadata.
+func (u *User) OpenGroup(groupID int64) *Group {
+	return &Group{
+		client:  u.client,
+		GroupID: groupID,
+	}
+}
+
+// ListGroups retrieves the user's groups as Group values with their metadata loaded
+func (u *User) ListGroups(ctx context.Context) ([]*Group, error) {
+	groupIDs, err := u.GetGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make([]*Group, 0, len(groupIDs))
+	for _, groupID := range groupIDs {
+		group := u.OpenGroup(groupID)
+		if _, err := group.Refresh(ctx); err != nil {
+			return nil, fmt.Errorf("failed to load group %d: %w", groupID, err)
+		}
+		groups = append(groups, group)
+	}
+
+	return groups, nil
+}
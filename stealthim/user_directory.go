@@ -0,0 +1,180 @@
+package stealthim
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// UserDirectoryOptions holds options for a UserDirectory
+type UserDirectoryOptions struct {
+	TTL         time.Duration // How long profiles are cached
+	NegativeTTL time.Duration // How long lookups the server rejected are remembered, 0 to not cache them
+	Concurrency int           // Maximum parallel lookups in Resolve
+}
+
+// DefaultUserDirectoryOptions returns default options for a user directory
+func DefaultUserDirectoryOptions() *UserDirectoryOptions {
+	return &UserDirectoryOptions{
+		TTL:         5 * time.Minute,
+		NegativeTTL: time.Minute,
+		Concurrency: 4,
+	}
+}
+
+// UserDirectory caches public user profiles, for example to show nicknames in a message list.
+// Concurrent lookups of the same user share a single request.
+type UserDirectory struct {
+	user *User
+	opts UserDirectoryOptions
+	now  func() time.Time
+
+	mu       sync.Mutex
+	entries  map[string]directoryEntry
+	inflight map[string]*directoryCall
+}
+
+// directoryEntry is a cached profile, or the cached rejection of a lookup if info is nil
+type directoryEntry struct {
+	info    *UserInfo
+	err     error
+	expires time.Time
+}
+
+// directoryCall is a lookup in progress that other callers can wait for
+type directoryCall struct {
+	done chan struct{}
+	info *UserInfo
+	err  error
+}
+
+// NewUserDirectory creates a profile cache that looks up users on behalf of user
+func NewUserDirectory(user *User, opts *UserDirectoryOptions) *UserDirectory {
+	if opts == nil {
+		opts = DefaultUserDirectoryOptions()
+	}
+	return &UserDirectory{
+		user:     user,
+		opts:     *opts,
+		now:      time.Now,
+		entries:  make(map[string]directoryEntry),
+		inflight: make(map[string]*directoryCall),
+	}
+}
+
+// Lookup returns the profile of username, from the cache if possible
+func (d *UserDirectory) Lookup(ctx context.Context, username string) (*UserInfo, error) {
+	d.mu.Lock()
+	if entry, ok := d.entries[username]; ok && d.now().Before(entry.expires) {
+		d.mu.Unlock()
+		if entry.info == nil {
+			return nil, entry.err
+		}
+		info := *entry.info
+		return &info, nil
+	}
+
+	call, ok := d.inflight[username]
+	if !ok {
+		call = &directoryCall{done: make(chan struct{})}
+		d.inflight[username] = call
+		d.mu.Unlock()
+		// The request is shared, so it must not be cut short when this caller gives up
+		go d.fetch(context.WithoutCancel(ctx), username, call)
+	} else {
+		d.mu.Unlock()
+	}
+
+	select {
+	case <-call.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if call.err != nil {
+		return nil, call.err
+	}
+	info := *call.info
+	return &info, nil
+}
+
+// fetch loads a profile from the server, caches the outcome and releases waiting callers
+func (d *UserDirectory) fetch(ctx context.Context, username string, call *directoryCall) {
+	call.info, call.err = d.user.GetUserInfo(ctx, username)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.inflight, username)
+	switch {
+	case call.err == nil:
+		d.entries[username] = directoryEntry{info: call.info, expires: d.now().Add(d.opts.TTL)}
+	case isServerRejection(call.err) && d.opts.NegativeTTL > 0:
+		// The server does not document a "not found" code, so any rejection counts as a miss
+		d.entries[username] = directoryEntry{err: call.err, expires: d.now().Add(d.opts.NegativeTTL)}
+	}
+	close(call.done)
+}
+
+// Nickname returns the nickname of username, or username itself if it cannot be looked up
+func (d *UserDirectory) Nickname(ctx context.Context, username string) string {
+	info, err := d.Lookup(ctx, username)
+	if err != nil || info.Nickname == "" {
+		return username
+	}
+	return info.Nickname
+}
+
+// Resolve looks up several users at once, for example all distinct authors of a message list.
+// Usernames the server rejects, such as unknown users, are left out of the result. The error
+// is the first other failure; the profiles that could be resolved are returned either way.
+func (d *UserDirectory) Resolve(ctx context.Context, usernames []string) (map[string]*UserInfo, error) {
+	seen := make(map[string]bool, len(usernames))
+	unique := make([]string, 0, len(usernames))
+	for _, username := range usernames {
+		if !seen[username] {
+			seen[username] = true
+			unique = append(unique, username)
+		}
+	}
+
+	var mu sync.Mutex
+	result := make(map[string]*UserInfo, len(unique))
+	report := runBatch(ctx, unique, &BatchOptions{Concurrency: d.opts.Concurrency}, func(ctx context.Context, username string) error {
+		info, err := d.Lookup(ctx, username)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		result[username] = info
+		mu.Unlock()
+		return nil
+	})
+
+	codes := make([]int, 0, len(report.Failed))
+	for code := range report.Failed {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	for _, code := range codes {
+		for _, failure := range report.Failed[code] {
+			if !isServerRejection(failure.Err) {
+				return result, failure.Err
+			}
+		}
+	}
+	return result, nil
+}
+
+// Invalidate removes username from the cache, for example after a profile change
+func (d *UserDirectory) Invalidate(username string) {
+	d.mu.Lock()
+	delete(d.entries, username)
+	d.mu.Unlock()
+}
+
+// Purge empties the cache
+func (d *UserDirectory) Purge() {
+	d.mu.Lock()
+	d.entries = make(map[string]directoryEntry)
+	d.mu.Unlock()
+}
@@ -0,0 +1,170 @@
+package stealthim
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestUserDirectoryLookup tests that UserDirectory caches profiles until the TTL expires
+func TestUserDirectoryLookup(t *testing.T) {
+	fs := newFakeServer(t)
+	user := fs.login(t, "alice")
+	fs.login(t, "bob")
+	requests := countRequests(user.client)
+
+	dir := NewUserDirectory(user, &UserDirectoryOptions{TTL: time.Minute, NegativeTTL: time.Minute})
+	now := time.Now()
+	dir.now = func() time.Time { return now }
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		info, err := dir.Lookup(ctx, "bob")
+		if err != nil {
+			t.Fatalf("Lookup failed: %v", err)
+		}
+		if info.Username != "bob" || info.Nickname != "bob" {
+			t.Errorf("Unexpected profile: %+v", info)
+		}
+	}
+	if *requests != 1 {
+		t.Errorf("Expected 1 request, got %d", *requests)
+	}
+	if name := dir.Nickname(ctx, "bob"); name != "bob" {
+		t.Errorf("Expected nickname bob, got %q", name)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, err := dir.Lookup(ctx, "bob"); err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	if *requests != 2 {
+		t.Errorf("Expected the expired entry to be refetched, got %d requests", *requests)
+	}
+
+	dir.Invalidate("bob")
+	if _, err := dir.Lookup(ctx, "bob"); err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	if *requests != 3 {
+		t.Errorf("Expected the invalidated entry to be refetched, got %d requests", *requests)
+	}
+}
+
+// TestUserDirectoryNotFound tests that lookups of unknown usernames are cached as rejected
+func TestUserDirectoryNotFound(t *testing.T) {
+	fs := newFakeServer(t)
+	user := fs.login(t, "alice")
+	requests := countRequests(user.client)
+
+	dir := NewUserDirectory(user, nil)
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if _, err := dir.Lookup(ctx, "ghost"); resultCode(err) != fakeCodeUserNotFound {
+			t.Fatalf("Expected the server's rejection, got %v", err)
+		}
+	}
+	if *requests != 1 {
+		t.Errorf("Expected the miss to be cached, got %d requests", *requests)
+	}
+	if name := dir.Nickname(ctx, "ghost"); name != "ghost" {
+		t.Errorf("Expected the username as fallback, got %q", name)
+	}
+}
+
+// TestUserDirectoryCoalescing tests that concurrent lookups of the same user share one request
+func TestUserDirectoryCoalescing(t *testing.T) {
+	fs := newFakeServer(t)
+	user := fs.login(t, "alice")
+	fs.login(t, "bob")
+
+	var mu sync.Mutex
+	requests := 0
+	user.client.Use(func(ctx context.Context, call *Call, next Invoker) (*Reply, error) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+		time.Sleep(50 * time.Millisecond)
+		return next(ctx, call)
+	})
+
+	dir := NewUserDirectory(user, nil)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := dir.Lookup(context.Background(), "bob"); err != nil {
+				t.Errorf("Lookup failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if requests != 1 {
+		t.Errorf("Expected 1 request, got %d", requests)
+	}
+}
+
+// TestUserDirectoryCancelledLeader tests that cancelling the first caller does not fail the others
+func TestUserDirectoryCancelledLeader(t *testing.T) {
+	fs := newFakeServer(t)
+	user := fs.login(t, "alice")
+	fs.login(t, "bob")
+
+	started, release := make(chan struct{}), make(chan struct{})
+	user.client.Use(func(ctx context.Context, call *Call, next Invoker) (*Reply, error) {
+		close(started)
+		<-release
+		return next(ctx, call)
+	})
+
+	dir := NewUserDirectory(user, nil)
+	leaderCtx, cancel := context.WithCancel(context.Background())
+	leader := make(chan error)
+	go func() {
+		_, err := dir.Lookup(leaderCtx, "bob")
+		leader <- err
+	}()
+	<-started
+
+	waiter := make(chan error)
+	go func() {
+		_, err := dir.Lookup(context.Background(), "bob")
+		waiter <- err
+	}()
+
+	cancel()
+	if err := <-leader; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the leader to be cancelled, got %v", err)
+	}
+	close(release)
+	if err := <-waiter; err != nil {
+		t.Errorf("Expected the waiter to get the profile, got %v", err)
+	}
+}
+
+// TestUserDirectoryResolve tests the UserDirectory.Resolve method
+func TestUserDirectoryResolve(t *testing.T) {
+	fs := newFakeServer(t)
+	user := fs.login(t, "alice")
+	fs.login(t, "bob")
+	fs.login(t, "carol")
+	requests := countRequests(user.client)
+
+	// countRequests is not safe for parallel calls, so resolve one user at a time
+	opts := DefaultUserDirectoryOptions()
+	opts.Concurrency = 1
+	dir := NewUserDirectory(user, opts)
+	profiles, err := dir.Resolve(context.Background(), []string{"bob", "carol", "bob", "ghost"})
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if len(profiles) != 2 || profiles["bob"] == nil || profiles["carol"] == nil {
+		t.Errorf("Unexpected profiles: %v", profiles)
+	}
+	if *requests != 3 {
+		t.Errorf("Expected one request per distinct user, got %d", *requests)
+	}
+}
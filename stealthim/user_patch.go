@@ -0,0 +1,104 @@
+package stealthim
+
+import (
+	"context"
+	"fmt"
+)
+
+// UserInfoPatch describes a partial profile update for User.Patch.
+// Fields that are neither set nor cleared are left unchanged. The password is changed
+// through User.ChangePassword instead, which checks the current one.
+type UserInfoPatch struct {
+	fields map[string]string
+}
+
+// NewUserInfoPatch creates an empty patch
+func NewUserInfoPatch() *UserInfoPatch {
+	return &UserInfoPatch{fields: make(map[string]string)}
+}
+
+// SetNickname changes the nickname
+func (p *UserInfoPatch) SetNickname(nickname string) *UserInfoPatch {
+	return p.set("nickname", nickname)
+}
+
+// ClearNickname removes the nickname
+func (p *UserInfoPatch) ClearNickname() *UserInfoPatch {
+	return p.set("nickname", "")
+}
+
+// SetEmail changes the email address
+func (p *UserInfoPatch) SetEmail(email string) *UserInfoPatch {
+	return p.set("email", email)
+}
+
+// ClearEmail removes the email address
+func (p *UserInfoPatch) ClearEmail() *UserInfoPatch {
+	return p.set("email", "")
+}
+
+// SetPhoneNumber changes the phone number
+func (p *UserInfoPatch) SetPhoneNumber(phoneNumber string) *UserInfoPatch {
+	return p.set("phone_number", phoneNumber)
+}
+
+// ClearPhoneNumber removes the phone number
+func (p *UserInfoPatch) ClearPhoneNumber() *UserInfoPatch {
+	return p.set("phone_number", "")
+}
+
+// Empty reports whether the patch changes nothing
+func (p *UserInfoPatch) Empty() bool {
+	return len(p.fields) == 0
+}
+
+// set records a field change, the last call for a field wins
+func (p *UserInfoPatch) set(field, value string) *UserInfoPatch {
+	if p.fields == nil {
+		p.fields = make(map[string]string)
+	}
+	p.fields[field] = value
+	return p
+}
+
+// Patch applies a partial profile update and returns the refreshed profile.
+// Set fields are validated before the request is sent. Like UpdateInfo it does not re-check
+// the password, call VerifyPassword first if the patch should need it.
+func (u *User) Patch(ctx context.Context, patch *UserInfoPatch) (*UserInfo, error) {
+	if patch.Empty() {
+		return u.GetSelfInfo(ctx)
+	}
+	if err := validateFields(patch.fields); err != nil {
+		return nil, err
+	}
+
+	reqBody := make(map[string]any, len(patch.fields))
+	for field, value := range patch.fields {
+		reqBody[field] = value
+	}
+
+	resp, err := u.client.doRequest(ctx, "PUT", "/api/v1/user", reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("update info request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var response struct {
+		Result Result `json:"result"`
+	}
+	if err := u.client.parseResponse(resp, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse update info response: %w", err)
+	}
+
+	if !response.Result.IsSuccess() {
+		return nil, response.Result.ToError()
+	}
+
+	// GetSelfInfo also updates the cached profile
+	info, err := u.GetSelfInfo(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("profile updated but refreshing it failed: %w", err)
+	}
+
+	return info, nil
+}
@@ -0,0 +1,52 @@
+package stealthim
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// TestUserPatch tests the User.Patch method
+func TestUserPatch(t *testing.T) {
+	fs := newFakeServer(t)
+	user := fs.login(t, "alice")
+	ctx := context.Background()
+
+	info, err := user.Patch(ctx, NewUserInfoPatch().SetNickname("Alice").ClearPhoneNumber())
+	if err != nil {
+		t.Fatalf("Patch failed: %v", err)
+	}
+	if info.Nickname != "Alice" || info.PhoneNumber != "" || info.Email != "alice@example.com" {
+		t.Errorf("Unexpected profile after patch: %+v", info)
+	}
+	if user.info.Nickname != "Alice" || user.info.PhoneNumber != "" {
+		t.Errorf("Expected the cached profile to be updated, got %+v", user.info)
+	}
+
+	// Later calls for the same field win
+	info, err = user.Patch(ctx, NewUserInfoPatch().ClearNickname().SetNickname("Al"))
+	if err != nil {
+		t.Fatalf("Patch failed: %v", err)
+	}
+	if info.Nickname != "Al" {
+		t.Errorf("Expected nickname Al, got %q", info.Nickname)
+	}
+}
+
+// TestUserPatchValidation tests that invalid patches are rejected before any request is sent
+func TestUserPatchValidation(t *testing.T) {
+	fs := newFakeServer(t)
+	user := fs.login(t, "alice")
+	requests := countRequests(user.client)
+	ctx := context.Background()
+
+	if _, err := user.Patch(ctx, NewUserInfoPatch().SetEmail("not-an-email")); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected a validation error for the email, got %v", err)
+	}
+	if _, err := user.Patch(ctx, NewUserInfoPatch().SetPhoneNumber("12ab")); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected a validation error for the phone number, got %v", err)
+	}
+	if *requests != 0 {
+		t.Errorf("Expected no requests, got %d", *requests)
+	}
+}
@@ -0,0 +1,65 @@
+package stealthim
+
+import "context"
+
+// InfoChangeFunc is called with the previous and the new profile after it changed
+type InfoChangeFunc func(old, new UserInfo)
+
+// Info returns the cached profile of the user, as of login or the last successful change or refresh
+func (u *User) Info() UserInfo {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.info
+}
+
+// Refresh reloads the profile from the server and updates the cache
+func (u *User) Refresh(ctx context.Context) (*UserInfo, error) {
+	return u.GetSelfInfo(ctx)
+}
+
+// OnInfoChange registers fn to be called whenever the cached profile changes, for example
+// after ChangeNickname or Refresh. Listeners run synchronously on the calling goroutine.
+// The returned function removes the listener.
+func (u *User) OnInfoChange(fn InfoChangeFunc) (remove func()) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.listeners == nil {
+		u.listeners = make(map[int]InfoChangeFunc)
+	}
+	id := u.nextListener
+	u.nextListener++
+	u.listeners[id] = fn
+
+	return func() {
+		u.mu.Lock()
+		delete(u.listeners, id)
+		u.mu.Unlock()
+	}
+}
+
+// updateInfo applies fn to the cached profile and notifies listeners if it changed
+func (u *User) updateInfo(fn func(info *UserInfo)) {
+	u.mu.Lock()
+	old := u.info
+	fn(&u.info)
+	updated := u.info
+	listeners := make([]InfoChangeFunc, 0, len(u.listeners))
+	for _, listener := range u.listeners {
+		listeners = append(listeners, listener)
+	}
+	u.mu.Unlock()
+
+	if old == updated {
+		return
+	}
+	for _, listener := range listeners {
+		listener(old, updated)
+	}
+}
+
+// setInfo replaces the cached profile and notifies listeners if it changed
+func (u *User) setInfo(info UserInfo) {
+	u.updateInfo(func(cached *UserInfo) {
+		*cached = info
+	})
+}
@@ -0,0 +1,67 @@
+package stealthim
+
+import (
+	"context"
+	"testing"
+)
+
+// TestUserInfoChanges tests that profile changes update User.Info and notify listeners
+func TestUserInfoChanges(t *testing.T) {
+	fs := newFakeServer(t)
+	user := fs.login(t, "alice")
+	ctx := context.Background()
+
+	if info := user.Info(); info.Username != "alice" || info.Nickname != "alice" {
+		t.Fatalf("Expected the login profile, got %+v", info)
+	}
+
+	var changes []UserInfo
+	remove := user.OnInfoChange(func(old, new UserInfo) {
+		if old == new {
+			t.Errorf("Listener called without a change: %+v", new)
+		}
+		changes = append(changes, new)
+	})
+
+	requests := countRequests(user.client)
+	if err := user.ChangeNickname(ctx, "Alice"); err != nil {
+		t.Fatalf("ChangeNickname failed: %v", err)
+	}
+	if err := user.ChangeEmail(ctx, "alice@example.org"); err != nil {
+		t.Fatalf("ChangeEmail failed: %v", err)
+	}
+	if err := user.ChangePhoneNumber(ctx, "5550100"); err != nil {
+		t.Fatalf("ChangePhoneNumber failed: %v", err)
+	}
+	if err := user.UpdateInfo(ctx, "", "Ally", ""); err != nil {
+		t.Fatalf("UpdateInfo failed: %v", err)
+	}
+	if *requests != 4 {
+		t.Errorf("Expected no extra requests, got %d", *requests)
+	}
+
+	info := user.Info()
+	if info.Nickname != "Ally" || info.Email != "alice@example.org" || info.PhoneNumber != "5550100" {
+		t.Errorf("Unexpected cached profile: %+v", info)
+	}
+	if len(changes) != 4 || changes[0].Nickname != "Alice" {
+		t.Errorf("Expected 4 notifications, got %+v", changes)
+	}
+
+	// Refreshing an unchanged profile does not notify
+	refreshed, err := user.Refresh(ctx)
+	if err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	if *refreshed != info || len(changes) != 4 {
+		t.Errorf("Expected the server profile %+v to match the cache %+v", refreshed, info)
+	}
+
+	remove()
+	if err := user.ChangeNickname(ctx, "Alicia"); err != nil {
+		t.Fatalf("ChangeNickname failed: %v", err)
+	}
+	if len(changes) != 4 {
+		t.Errorf("Expected no notification after removing the listener, got %d", len(changes))
+	}
+}
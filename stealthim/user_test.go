@@ -100,7 +100,7 @@ func TestUserChangePassword(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 	
-	err = user.ChangePassword(ctx, "Ab123456")
+	err = user.ChangePassword(ctx, "Ab123456", "Ab123456")
 	if err != nil {
 		t.Errorf("Failed to change password: %v", err)
 	}
@@ -224,7 +224,7 @@ func TestUserUpdateInfo(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 	
-	err = user.UpdateInfo(ctx, "Ab123456", "newemail@example.com", "New Nickname", "0987654321")
+	err = user.UpdateInfo(ctx, "newemail@example.com", "New Nickname", "0987654321")
 	if err != nil {
 		t.Errorf("Failed to update info: %v", err)
 	}
@@ -255,7 +255,11 @@ func TestUserDelete(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 	
-	err = user.Delete(ctx)
+	confirm, err := user.RequestDelete(ctx, "Ab123456")
+	if err != nil {
+		t.Fatalf("Failed to confirm deletion: %v", err)
+	}
+	err = user.Delete(ctx, confirm)
 	if err != nil {
 		t.Errorf("Failed to delete user: %v", err)
 	}
@@ -293,4 +297,52 @@ func TestUserGetGroups(t *testing.T) {
 	if groups == nil {
 		t.Error("Expected groups to be non-nil")
 	}
-}
\ No newline at end of file
+}
+
+// TestUserOpenGroup tests the User.OpenGroup method
+func TestUserOpenGroup(t *testing.T) {
+	fs := newFakeServer(t)
+	owner := fs.login(t, "alice")
+	member := fs.login(t, "bob")
+	created := fs.createGroup(t, owner, "Team", "bob")
+
+	group := member.OpenGroup(created.GroupID)
+	if group.GroupID != created.GroupID {
+		t.Errorf("Expected group ID %d, got %d", created.GroupID, group.GroupID)
+	}
+
+	if _, err := group.SendText(context.Background(), "hello"); err != nil {
+		t.Errorf("Failed to use opened group: %v", err)
+	}
+}
+
+// TestUserListGroups tests the User.ListGroups method
+func TestUserListGroups(t *testing.T) {
+	fs := newFakeServer(t)
+	user := fs.login(t, "alice")
+	fs.createGroup(t, user, "First")
+	fs.createGroup(t, user, "Second")
+
+	groups, err := user.ListGroups(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to list groups: %v", err)
+	}
+	if len(groups) != 2 {
+		t.Fatalf("Expected 2 groups, got %d", len(groups))
+	}
+
+	names := map[string]bool{}
+	for _, group := range groups {
+		info, err := group.Info(context.Background())
+		if err != nil {
+			t.Fatalf("Failed to get group info: %v", err)
+		}
+		names[info.Name] = true
+		if info.Role != Owner {
+			t.Errorf("Expected owner role in group %d, got %d", group.GroupID, info.Role)
+		}
+	}
+	if !names["First"] || !names["Second"] {
+		t.Errorf("Unexpected group names: %v", names)
+	}
+}
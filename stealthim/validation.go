@@ -0,0 +1,149 @@
+package stealthim
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// ErrValidation is matched by every *ValidationError with errors.Is
+var ErrValidation = errors.New("validation failed")
+
+// Validation limits
+const (
+	MinUsernameLength = 3
+	MaxUsernameLength = 20
+	MinPasswordLength = 8
+	MaxPasswordLength = 64
+)
+
+// ValidationError reports an invalid request field, either found before the request is sent
+// or rejected by the server
+type ValidationError struct {
+	Field  string // Request field, such as "username" or "phone_number"
+	Reason string
+	Err    error // Server error behind the rejection, nil for client-side checks
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid %s: %s", e.Field, e.Reason)
+}
+
+// Is makes ValidationError match ErrValidation
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrValidation
+}
+
+// Unwrap returns the server error, if any
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationErrors collects the errors of all invalid fields in a request.
+// Use errors.As to get the first *ValidationError, or range over it for all of them.
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Unwrap returns the individual field errors
+func (e ValidationErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// ValidateUsername checks that a username has 3 to 20 letters, digits or underscores
+func ValidateUsername(username string) error {
+	if len(username) < MinUsernameLength || len(username) > MaxUsernameLength {
+		return &ValidationError{Field: "username", Reason: fmt.Sprintf("must be %d to %d characters", MinUsernameLength, MaxUsernameLength)}
+	}
+	for _, r := range username {
+		if r > unicode.MaxASCII || !(unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_') {
+			return &ValidationError{Field: "username", Reason: "may only contain letters, digits and underscores"}
+		}
+	}
+	return nil
+}
+
+// ValidatePassword checks that a password has 8 to 64 characters with at least one letter and one digit
+func ValidatePassword(password string) error {
+	if len(password) < MinPasswordLength || len(password) > MaxPasswordLength {
+		return &ValidationError{Field: "password", Reason: fmt.Sprintf("must be %d to %d characters", MinPasswordLength, MaxPasswordLength)}
+	}
+	var hasLetter, hasDigit bool
+	for _, r := range password {
+		hasLetter = hasLetter || unicode.IsLetter(r)
+		hasDigit = hasDigit || unicode.IsDigit(r)
+	}
+	if !hasLetter || !hasDigit {
+		return &ValidationError{Field: "password", Reason: "must contain letters and digits"}
+	}
+	return nil
+}
+
+// ValidateEmail checks that email is a plain address such as user@example.com
+func ValidateEmail(email string) error {
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email || !strings.Contains(email[strings.LastIndex(email, "@"):], ".") {
+		return &ValidationError{Field: "email", Reason: "is not a valid email address"}
+	}
+	return nil
+}
+
+// ValidatePhoneNumber checks that a phone number has 5 to 15 digits, optionally prefixed with +
+func ValidatePhoneNumber(phoneNumber string) error {
+	digits := strings.TrimPrefix(phoneNumber, "+")
+	if len(digits) < 5 || len(digits) > 15 {
+		return &ValidationError{Field: "phone_number", Reason: "must have 5 to 15 digits"}
+	}
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return &ValidationError{Field: "phone_number", Reason: "may only contain digits and a leading +"}
+		}
+	}
+	return nil
+}
+
+// validateFields runs the validators for the non-empty fields and collects the failures.
+// Fields listed in required are validated even if empty.
+func validateFields(fields map[string]string, required ...string) error {
+	validators := []struct {
+		field    string
+		validate func(string) error
+	}{
+		{"username", ValidateUsername},
+		{"password", ValidatePassword},
+		{"email", ValidateEmail},
+		{"phone_number", ValidatePhoneNumber},
+	}
+
+	var errs ValidationErrors
+	for _, v := range validators {
+		value, ok := fields[v.field]
+		if !ok {
+			continue
+		}
+		if value == "" && !slices.Contains(required, v.field) {
+			continue
+		}
+		var fieldErr *ValidationError
+		if err := v.validate(value); errors.As(err, &fieldErr) {
+			errs = append(errs, fieldErr)
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
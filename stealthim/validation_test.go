@@ -0,0 +1,95 @@
+package stealthim
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// TestValidators tests the field validators
+func TestValidators(t *testing.T) {
+	tests := []struct {
+		name     string
+		validate func(string) error
+		value    string
+		valid    bool
+	}{
+		{"username", ValidateUsername, "alice_01", true},
+		{"short username", ValidateUsername, "al", false},
+		{"long username", ValidateUsername, "a_very_long_username_x", false},
+		{"username charset", ValidateUsername, "alice-01", false},
+		{"unicode username", ValidateUsername, "älice", false},
+		{"password", ValidatePassword, "Ab123456", true},
+		{"short password", ValidatePassword, "Ab1234", false},
+		{"password without digits", ValidatePassword, "abcdefgh", false},
+		{"password without letters", ValidatePassword, "12345678", false},
+		{"email", ValidateEmail, "alice@example.com", true},
+		{"email without domain", ValidateEmail, "alice@", false},
+		{"email without tld", ValidateEmail, "alice@localhost", false},
+		{"email with name", ValidateEmail, "Alice <alice@example.com>", false},
+		{"phone", ValidatePhoneNumber, "1234567890", true},
+		{"international phone", ValidatePhoneNumber, "+8613800000000", true},
+		{"short phone", ValidatePhoneNumber, "123", false},
+		{"phone with letters", ValidatePhoneNumber, "12345abc", false},
+	}
+	for _, tt := range tests {
+		err := tt.validate(tt.value)
+		if tt.valid && err != nil {
+			t.Errorf("%s: expected %q to be valid, got %v", tt.name, tt.value, err)
+		}
+		if !tt.valid && !errors.Is(err, ErrValidation) {
+			t.Errorf("%s: expected %q to be invalid, got %v", tt.name, tt.value, err)
+		}
+	}
+}
+
+// TestRegisterValidation tests that Register reports all invalid fields without sending a request
+func TestRegisterValidation(t *testing.T) {
+	fs := newFakeServer(t)
+	server := NewServer(fs.URL)
+	requests := countRequests(server.client)
+
+	_, err := server.Register(context.Background(), "a!", "weak", "Alice", "not-an-email", "1234567890")
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Expected ValidationErrors, got %v", err)
+	}
+	fields := map[string]bool{}
+	for _, e := range errs {
+		fields[e.Field] = true
+	}
+	if len(errs) != 3 || !fields["username"] || !fields["password"] || !fields["email"] {
+		t.Errorf("Unexpected validation errors: %v", errs)
+	}
+	var fieldErr *ValidationError
+	if !errors.As(err, &fieldErr) || fieldErr.Field != "username" {
+		t.Errorf("Expected the first field error to be for username, got %v", fieldErr)
+	}
+	if *requests != 0 {
+		t.Errorf("Expected no requests, got %d", *requests)
+	}
+}
+
+// TestUpdateInfoValidation tests that profile updates validate only the fields being changed
+func TestUpdateInfoValidation(t *testing.T) {
+	fs := newFakeServer(t)
+	user := fs.login(t, "alice")
+
+	if err := user.UpdateInfo(context.Background(), "", "New Nickname", "abc"); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected a validation error for the phone number, got %v", err)
+	}
+	if err := user.ChangePassword(context.Background(), "Ab123456", "short"); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected a validation error for the password, got %v", err)
+	}
+
+	requests := countRequests(user.client)
+	if err := user.ChangeEmail(context.Background(), "not-an-email"); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected a validation error for the email, got %v", err)
+	}
+	if err := user.ChangePhoneNumber(context.Background(), "12ab"); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected a validation error for the phone number, got %v", err)
+	}
+	if *requests != 0 {
+		t.Errorf("Expected invalid input to be rejected locally, got %d requests", *requests)
+	}
+}